@@ -16,8 +16,10 @@ import (
 	// "strings"
 	"fmt"
 	"reflect"
+	"sync"
 )
 
+// defaults of Config.DebugMutation and Config.SetCreatedUpdatedTimeOnSave
 var DEBUG_MUTATION bool = true
 var SetCreatedUpdatedTimeOnSave bool = true
 
@@ -35,6 +37,87 @@ type ModelBinder struct {
 	ModelNew *bool
 
 	Context context.Context
+
+	changeCallbacks map[string][]ChangeCallback
+
+	lock *sync.RWMutex //nil unless Concurrent()
+
+	err error //set when the binder failed to initialise, see Err
+}
+
+// enable locking around Set, Changes access and IsNew
+// for binders shared by concurrent graphql resolvers
+// fields of the model itself should only be changed via Set
+func (this *ModelBinder) Concurrent() *ModelBinder {
+	if this.lock == nil {
+		this.lock = &sync.RWMutex{}
+	}
+	return this
+}
+
+// error raised while constructing the binder (eg BinderOnChange), nil if none
+// a binder with an error refuses to save
+func (this *ModelBinder) Err() error {
+	defer this.readLock()()
+	return this.err
+}
+
+func (this *ModelBinder) SetErr(err error) {
+	defer this.writeLock()()
+	this.err = err
+}
+
+func (this *ModelBinder) IsConcurrent() bool {
+	return this.lock != nil
+}
+
+// usage: defer this.writeLock()()
+func (this *ModelBinder) writeLock() func() {
+	if this.lock == nil {
+		return func() {}
+	}
+	this.lock.Lock()
+	return this.lock.Unlock
+}
+
+func (this *ModelBinder) readLock() func() {
+	if this.lock == nil {
+		return func() {}
+	}
+	this.lock.RLock()
+	return this.lock.RUnlock
+}
+
+// copy of Changes, safe to iterate while other goroutines Set
+func (this *ModelBinder) ChangesSnapshot() map[string][]interface{} {
+	defer this.readLock()()
+	changes := make(map[string][]interface{}, len(this.Changes))
+	for name, change := range this.Changes {
+		changes[name] = []interface{}{change[0], change[1]}
+	}
+	return changes
+}
+
+// called with [old, new] whenever Set records a change on the field, returning error aborts the Set
+type ChangeCallback func(old interface{}, new interface{}) error
+
+// register a callback fired by SetValue when a change is recorded for field name
+func (this *ModelBinder) OnChange(name string, callback ChangeCallback) *ModelBinder {
+	defer this.writeLock()()
+	if this.changeCallbacks == nil {
+		this.changeCallbacks = map[string][]ChangeCallback{}
+	}
+	this.changeCallbacks[name] = append(this.changeCallbacks[name], callback)
+	return this
+}
+
+func fireChange(callbacks []ChangeCallback, change []interface{}) error {
+	for _, callback := range callbacks {
+		if err := callback(change[0], change[1]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (this *ModelBinder) SetsFromJSON(values map[string]interface{}, markChanged bool) error {
@@ -59,7 +142,7 @@ func (this *ModelBinder) SetsFromJSON(values map[string]interface{}, markChanged
 		}
 		// logging(this.Context).Debugf("Set %s=%#v", fieldName, values[i])
 		if err := this.Set(fieldName, values[i], markChanged); err != nil {
-			err2 := fmt.Errorf("Error setting %s=%+v, error: %+v", fieldName, values[i], err)
+			err2 := fmt.Errorf("Error setting %s=%+v, error: %+v", fieldName, this.redactValue(fieldName, values[i]), err)
 			return err2
 		}
 	}
@@ -70,7 +153,7 @@ func (this *ModelBinder) SetsFromJSON(values map[string]interface{}, markChanged
 func (this *ModelBinder) Sets(values map[string]interface{}, markChanged bool) error {
 	for i := range values {
 		if err := this.Set(i, values[i], markChanged); err != nil {
-			err2 := fmt.Errorf("Error setting %s=%+v, error: %+v", i, values[i], err)
+			err2 := fmt.Errorf("Error setting %s=%+v, error: %+v", i, this.redactValue(i, values[i]), err)
 			return err2
 		}
 	}
@@ -83,6 +166,7 @@ func (this *ModelBinder) Model() interface{} {
 }
 
 func (this *ModelBinder) IsNew() bool {
+	defer this.writeLock()()
 	if this.ModelNew != nil {
 		return *this.ModelNew
 	}
@@ -105,6 +189,11 @@ func (this *ModelBinder) IsNew() bool {
 }
 
 func (this *ModelBinder) ResetChange(name string) {
+	defer this.writeLock()()
+	this.resetChange(name)
+}
+
+func (this *ModelBinder) resetChange(name string) {
 	model := reflect.ValueOf(this.model).Elem()
 	field := model.FieldByName(name)
 
@@ -117,7 +206,21 @@ func (this *ModelBinder) ResetChange(name string) {
 	}
 }
 
+// put field back to value with its earlier recorded change (nil drops the change), callbacks are not fired
+// used to undo a Set, example: timestamps of a failed save
+func (this *ModelBinder) Restore(name string, value interface{}, change []interface{}) {
+	defer this.writeLock()()
+	field := reflect.ValueOf(this.model).Elem().FieldByName(name)
+	field.Set(reflect.ValueOf(value))
+	if change == nil {
+		delete(this.Changes, name)
+	} else {
+		this.Changes[name] = change
+	}
+}
+
 func (this *ModelBinder) Changed(name string) bool {
+	defer this.readLock()()
 	model := reflect.ValueOf(this.model).Elem()
 	field := model.FieldByName(name)
 
@@ -132,6 +235,7 @@ func (this *ModelBinder) Changed(name string) bool {
 }
 
 func (this *ModelBinder) Dirty() bool {
+	defer this.readLock()()
 	for _ = range this.Changes {
 		return true
 	}
@@ -139,24 +243,75 @@ func (this *ModelBinder) Dirty() bool {
 }
 
 func (this *ModelBinder) SetValue(name string, value reflect.Value, markChanged bool) error {
+	//set and record change under one lock, so Changes always matches the field
+	unlock := this.writeLock()
+	callbacks := this.changeCallbacks[name]
+	//model and changes before this Set, restored when a callback aborts,
+	//undoing Sets made by the callbacks as well
+	var savedModel reflect.Value
+	var savedChanges map[string][]interface{}
+	if markChanged && len(callbacks) > 0 {
+		savedModel = reflect.New(reflect.TypeOf(this.model).Elem()).Elem()
+		savedModel.Set(reflect.ValueOf(this.model).Elem())
+		savedChanges = make(map[string][]interface{}, len(this.Changes))
+		for changed, change := range this.Changes {
+			savedChanges[changed] = change
+		}
+	}
+	_, change, err := this.setValue(name, value, markChanged)
+	if err != nil || change == nil {
+		unlock()
+		return err
+	}
+	this.Changes[name] = change
+	unlock()
+
+	//callbacks run unlocked as they may Set other fields
+	if err := fireChange(callbacks, change); err != nil {
+		defer this.writeLock()()
+		//abort, restore model and changes unless another Set replaced the value meanwhile
+		field := reflect.ValueOf(this.model).Elem().FieldByName(name)
+		if !reflect.DeepEqual(field.Interface(), change[1]) {
+			return err
+		}
+		reflect.ValueOf(this.model).Elem().Set(savedModel)
+		//same map, binders copied from this one share it
+		for changed := range this.Changes {
+			delete(this.Changes, changed)
+		}
+		for changed, change := range savedChanges {
+			this.Changes[changed] = change
+		}
+		return err
+	}
+	return nil
+}
+
+// bind value onto field
+// @return original value, [old, new] change to be recorded (nil if unchanged), error
+func (this *ModelBinder) setValue(name string, value reflect.Value, markChanged bool) (interface{}, []interface{}, error) {
 	model := reflect.ValueOf(this.model).Elem()
 	field := model.FieldByName(name)
 
 	if !field.CanSet() {
-		return fmt.Errorf("Cannot set field: %v:%s", model.Type().Name(), name)
+		return nil, nil, fmt.Errorf("Cannot set field: %v:%s", model.Type().Name(), name)
 	}
 
-	oriValue := this.Get(name)
-	err := BindFieldValue(this.Context, name, &field, value)
+	oriValue := this.get(name)
+	ctx := this.Context
+	if this.isSensitive(name) {
+		ctx = withSensitive(ctx)
+	}
+	err := BindFieldValue(ctx, name, &field, value)
 	if err != nil {
-		return fmt.Errorf("Cannot set field: %v %+v", model.Type().Name(), err)
+		return oriValue, nil, fmt.Errorf("Cannot set field: %v %+v", model.Type().Name(), err)
 	}
 
 	if !markChanged {
-		return nil
+		return oriValue, nil, nil
 	}
 
-	newValue := this.Get(name)
+	newValue := this.get(name)
 	newValueReflect := reflect.ValueOf(newValue)
 	newValueIsNilPtr := reflect.ValueOf(newValue).Kind() == reflect.Ptr && reflect.ValueOf(newValue).IsNil()
 
@@ -167,12 +322,11 @@ func (this *ModelBinder) SetValue(name string, value reflect.Value, markChanged
 			if !reflect.ValueOf(oriValue).IsValid() || reflect.ValueOf(oriValue).IsNil() {
 				if !newValueReflect.IsValid() || newValueReflect.IsNil() {
 					//same nil
-					return nil
+					return oriValue, nil, nil
 				}
 
 				//changed and new value isnt nil
-				this.Changes[name] = []interface{}{oriValue, newValue}
-				return nil
+				return oriValue, []interface{}{oriValue, newValue}, nil
 			}
 
 			// if !reflect.ValueOf(oriValue).IsValid() || reflect.ValueOf(oriValue).IsNil() { //nil value
@@ -185,24 +339,24 @@ func (this *ModelBinder) SetValue(name string, value reflect.Value, markChanged
 
 			if reflect.ValueOf(oriValue).IsValid() || !reflect.ValueOf(oriValue).IsNil() {
 				if newValueIsNilPtr {
-					this.Changes[name] = []interface{}{oriValue, reflect.Zero(newValueReflect.Type()).Interface()}
-					return nil
+					return oriValue, []interface{}{oriValue, reflect.Zero(newValueReflect.Type()).Interface()}, nil
 				}
 			}
 		}
 		if !newValueIsNilPtr && oldValue != reflect.ValueOf(newValue).Elem().Interface() {
-			this.Changes[name] = []interface{}{oriValue, newValue}
+			return oriValue, []interface{}{oriValue, newValue}, nil
 		}
 	} else {
 		if eq, _ := lib.IsEqualValue(this.Context, oriValue, newValue); !eq {
-			this.Changes[name] = []interface{}{oriValue, newValue}
+			return oriValue, []interface{}{oriValue, newValue}, nil
 		}
 	}
 
-	return nil
+	return oriValue, nil, nil
 }
 
 func (this *ModelBinder) ResetRelation() error {
+	defer this.writeLock()()
 	if err := ForEachField(this.Model(), func(i int, fieldVal *reflect.Value, fieldStruct reflect.StructField) error {
 		// logging(this.Context).Debugf("field: %s=%#v", fieldStruct.Name, fieldVal)
 		if !reflect.Indirect(*fieldVal).IsValid() {
@@ -215,12 +369,12 @@ func (this *ModelBinder) ResetRelation() error {
 			newVal := reflect.Zero(fieldVal.Type())
 			fieldVal.Set(newVal)
 			// logging(this.Context).Debugf("Resetting struct: %s=%#v", fieldStruct.Name, newVal)
-			this.ResetChange(fieldStruct.Name)
+			this.resetChange(fieldStruct.Name)
 		} else if fieldType.Kind() == reflect.Slice {
 			newVal := reflect.Zero(fieldVal.Type())
 			fieldVal.Set(newVal)
 			// logging(this.Context).Debugf("Resetting slice: %s=%#v", fieldStruct.Name, newVal)
-			this.ResetChange(fieldStruct.Name)
+			this.resetChange(fieldStruct.Name)
 		}
 		return nil
 	}); err != nil {
@@ -230,17 +384,46 @@ func (this *ModelBinder) ResetRelation() error {
 	return nil
 }
 
-// https://play.golang.org/p/PmRkzehLlfa - test field.kind() vs field.type()
+// set relation fields (struct or slices of struct) to nil via SetValue, unlike ResetRelation
+// the clear is recorded, so RelationChanges reports the cleared elements as removed
+func (this *ModelBinder) ClearRelation() error {
+	modelType := reflect.TypeOf(this.Model()).Elem()
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if field.PkgPath != "" || !isRelationType(field.Type) {
+			continue
+		}
+		value := reflect.Zero(field.Type)
+		if field.Type.Kind() == reflect.Ptr {
+			value = reflect.Value{} //nil
+		}
+		if err := this.SetValue(field.Name, value, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func BindFieldValue(ctx context.Context, name string, pField *reflect.Value, value reflect.Value) error {
+	err := bindFieldValue(ctx, name, pField, value)
+	if err != nil {
+		ConfigFrom(ctx).MetricsRecorder().IncBindError(name)
+	}
+	return err
+}
+
+// https://play.golang.org/p/PmRkzehLlfa - test field.kind() vs field.type()
+func bindFieldValue(ctx context.Context, name string, pField *reflect.Value, value reflect.Value) error {
 	// gcontext.Logger.Debugf("Field: %#v", *pField)
 	if !pField.IsValid() {
-		logging(ctx).Warnf("Isnt valid-field: %s, %v", name, pField)
-		return fmt.Errorf("Field is not valid: %v:%v", name, pField)
+		structuredLogging(ctx).Warnw("invalid field", "field", name, "value", redactFor(ctx, name, pField))
+		return fmt.Errorf("Field is not valid: %v:%v", name, redactFor(ctx, name, pField))
 	}
 
 	if !pField.CanSet() {
-		return fmt.Errorf("Field passed in is not settable %s: %#v", name, pField)
+		return fmt.Errorf("Field passed in is not settable %s: %#v", name, redactFor(ctx, name, pField))
 	}
+	config := ConfigFrom(ctx)
 
 	//handling assigning to nil of any types
 	if pField.Kind() == reflect.Ptr && !value.IsValid() {
@@ -279,6 +462,15 @@ func BindFieldValue(ctx context.Context, name string, pField *reflect.Value, val
 		return nil
 	}
 
+	if fieldType == reflect.TypeOf(Geo{}) {
+		geo, err := ParseGeo(value.Interface())
+		if err != nil {
+			return fmt.Errorf("Unable to parse geo %s=%v: %+v", name, redactFor(ctx, name, value.Interface()), err)
+		}
+		field.Set(reflect.ValueOf(geo))
+		return nil
+	}
+
 	if fieldType.String() == "uuid.UUID" && value.Type().String() == "string" {
 
 		if value.Interface().(string) == "" {
@@ -288,7 +480,7 @@ func BindFieldValue(ctx context.Context, name string, pField *reflect.Value, val
 		} else {
 			uuidValue, err := uuid.FromString(value.Interface().(string))
 			if err != nil {
-				logging(ctx).Errorf("Unable to convert to uuid: %v", value.Interface())
+				structuredLogging(ctx).Errorw("unable to convert to uuid", "field", name, "value", redactFor(ctx, name, value.Interface()))
 			} else {
 				value = reflect.ValueOf(uuidValue)
 				field.Set(value)
@@ -308,10 +500,13 @@ func BindFieldValue(ctx context.Context, name string, pField *reflect.Value, val
 			} else {
 				var err error
 				// thetime, err = dateparse.ParseLocal(val)
-				thetime, err = ParseISODateTime(val)
+				thetime, err = config.ParseTime(val)
 				// thetime, err := tools.TimeFromISOString(val)
 				if err != nil {
-					logging(ctx).Errorf("Unable to parse time: %s=%v %s", name, val, err.Error())
+					structuredLogging(ctx).Errorw("unable to parse time", "field", name, "value", redactFor(ctx, name, val))
+					if config.Strict {
+						return fmt.Errorf("Unable to parse time: %s=%v", name, redactFor(ctx, name, val))
+					}
 					return nil
 				}
 			}
@@ -426,9 +621,12 @@ func BindFieldValue(ctx context.Context, name string, pField *reflect.Value, val
 					var err error
 					// thetime, err = dateparse.ParseLocal(val)
 					// thetime, err := tools.TimeFromISOString(val)
-					thetime, err = ParseISODateTime(val)
+					thetime, err = config.ParseTime(val)
 					if err != nil {
-						logging(ctx).Errorf("Unable to parse time: %s=%v %s", name, val, err.Error())
+						structuredLogging(ctx).Errorw("unable to parse time", "field", name, "value", redactFor(ctx, name, val))
+						if config.Strict {
+							return fmt.Errorf("Unable to parse time: %s=%v", name, redactFor(ctx, name, val))
+						}
 						return nil
 					}
 				}
@@ -453,7 +651,7 @@ func BindFieldValue(ctx context.Context, name string, pField *reflect.Value, val
 				field.Set(value)
 				return nil
 			} else {
-				logging(ctx).Errorf("Unable to convert to bool: %v", val)
+				structuredLogging(ctx).Errorw("unable to convert to bool", "field", name, "value", redactFor(ctx, name, val))
 			}
 		} //switch
 	case "int": // value field type
@@ -522,9 +720,9 @@ func BindFieldValue(ctx context.Context, name string, pField *reflect.Value, val
 					} else {
 						fieldValue := structVal.FieldByName(field.Name)
 
-						if err := BindFieldValue(ctx, k, &fieldValue, reflect.ValueOf(v)); err != nil {
+						if err := bindFieldValue(ctx, k, &fieldValue, reflect.ValueOf(v)); err != nil {
 							return fmt.Errorf("Unable to bind %v on %v, value: %v, error: %+v",
-								k, structVal.Type(), v, err)
+								k, structVal.Type(), redactFor(ctx, k, v), err)
 						}
 						// field.Set(v)
 					}
@@ -590,9 +788,9 @@ func BindFieldValue(ctx context.Context, name string, pField *reflect.Value, val
 				} else {
 					fieldValue := structVal.FieldByName(field.Name)
 
-					if err := BindFieldValue(ctx, k, &fieldValue, reflect.ValueOf(v)); err != nil {
+					if err := bindFieldValue(ctx, k, &fieldValue, reflect.ValueOf(v)); err != nil {
 						return fmt.Errorf("Unable to bind %v on %v, value: %v, error: %+v",
-							k, structVal.Type(), v, err)
+							k, structVal.Type(), redactFor(ctx, k, v), err)
 					}
 					// field.Set(v)
 				}
@@ -611,7 +809,7 @@ func BindFieldValue(ctx context.Context, name string, pField *reflect.Value, val
 		}
 	}
 
-	logging(ctx).Errorf("Data type mismatch field: %s, received: %v, expected: %v", name, strValueType, fieldType)
+	structuredLogging(ctx).Errorw("data type mismatch", "field", name, "received", strValueType, "expected", fieldType)
 	return fmt.Errorf("Data type mismatch field: %s, received: %v, expected: %v", name, strValueType, fieldType)
 }
 
@@ -622,6 +820,7 @@ func (this *ModelBinder) Set(name string, value interface{}, markChanged bool) e
 }
 
 func (this *ModelBinder) OldValue(name string) interface{} {
+	defer this.readLock()()
 	values, has := this.Changes[name]
 	if has {
 		return values[0]
@@ -640,6 +839,11 @@ func (this *ModelBinder) OldValue(name string) interface{} {
 }
 
 func (this *ModelBinder) Get(name string) interface{} {
+	defer this.readLock()()
+	return this.get(name)
+}
+
+func (this *ModelBinder) get(name string) interface{} {
 	model := reflect.ValueOf(this.model).Elem()
 	field := model.FieldByName(name)
 
@@ -0,0 +1,126 @@
+package gobinder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// json form of ModelBinder, for keeping half edited models (drafts) in session or cache
+type binderState struct {
+	Type     string                 `json:"type"`
+	Model    json.RawMessage        `json:"model"`
+	ModelNew *bool                  `json:"model_new"`
+	Changes  map[string]changeState `json:"changes"`
+	Err      string                 `json:"err,omitempty"` //message of Err, restored as a plain error
+}
+
+type changeState struct {
+	Type string          `json:"type"` //go type of old and new value
+	Old  json.RawMessage `json:"old"`
+	New  json.RawMessage `json:"new"`
+}
+
+// marshal model, Changes, ModelNew and Err
+// sensitive fields (see IsSensitiveField, including `dgraph:"password"`) are left out of the model
+// and changes, a resumed binder has them zero and unchanged so drafts never store secrets
+func (this *ModelBinder) MarshalJSON() ([]byte, error) {
+	defer this.readLock()()
+	modelType := reflect.TypeOf(this.model).Elem()
+
+	model := reflect.New(modelType)
+	model.Elem().Set(reflect.ValueOf(this.model).Elem())
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if field.PkgPath == "" && IsSensitiveField(field) {
+			model.Elem().Field(i).Set(reflect.Zero(field.Type))
+		}
+	}
+	modelJson, err := json.Marshal(model.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	state := binderState{
+		Type:     modelType.String(),
+		Model:    modelJson,
+		ModelNew: this.ModelNew,
+		Changes:  map[string]changeState{},
+	}
+	if this.err != nil {
+		state.Err = this.err.Error()
+	}
+	for name, change := range this.Changes {
+		field, ok := modelType.FieldByName(name)
+		if !ok {
+			return nil, fmt.Errorf("Cannot find field: %v:%s", modelType.Name(), name)
+		}
+		if IsSensitiveField(field) {
+			continue
+		}
+		oldJson, err := json.Marshal(change[0])
+		if err != nil {
+			return nil, fmt.Errorf("Unable to marshal old value of %s: %+v", name, err)
+		}
+		newJson, err := json.Marshal(change[1])
+		if err != nil {
+			return nil, fmt.Errorf("Unable to marshal new value of %s: %+v", name, err)
+		}
+		state.Changes[name] = changeState{Type: field.Type.String(), Old: oldJson, New: newJson}
+	}
+
+	return json.Marshal(state)
+}
+
+// restore state from MarshalJSON, model must already be set to a pointer of the same type
+func (this *ModelBinder) UnmarshalJSON(data []byte) error {
+	defer this.writeLock()()
+	if this.model == nil {
+		return fmt.Errorf("Binder model not set")
+	}
+	modelType := reflect.TypeOf(this.model).Elem()
+
+	var state binderState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Type != modelType.String() {
+		return fmt.Errorf("Binder state type mismatch: %s, expected: %s", state.Type, modelType.String())
+	}
+
+	changes := map[string][]interface{}{}
+	for name, change := range state.Changes {
+		field, ok := modelType.FieldByName(name)
+		if !ok {
+			return fmt.Errorf("Cannot find field: %v:%s", modelType.Name(), name)
+		}
+		if change.Type != field.Type.String() {
+			return fmt.Errorf("Change type mismatch %s: %s, expected: %s", name, change.Type, field.Type.String())
+		}
+
+		oldValue := reflect.New(field.Type)
+		if err := json.Unmarshal(change.Old, oldValue.Interface()); err != nil {
+			return fmt.Errorf("Unable to unmarshal old value of %s: %+v", name, err)
+		}
+		newValue := reflect.New(field.Type)
+		if err := json.Unmarshal(change.New, newValue.Interface()); err != nil {
+			return fmt.Errorf("Unable to unmarshal new value of %s: %+v", name, err)
+		}
+		changes[name] = []interface{}{oldValue.Elem().Interface(), newValue.Elem().Interface()}
+	}
+
+	model := reflect.New(modelType)
+	if err := json.Unmarshal(state.Model, model.Interface()); err != nil {
+		return err
+	}
+
+	reflect.ValueOf(this.model).Elem().Set(model.Elem())
+	this.ModelNew = state.ModelNew
+	this.Changes = changes
+	this.err = nil
+	if state.Err != "" {
+		this.err = errors.New(state.Err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package gobinder
+
+import (
+	"context"
+	"time"
+
+	"github.com/u007/gobinder/lib"
+)
+
+// behavior of binder and dgraph helpers, attach to DGraphTxn.Config or context with WithConfig
+// package globals (DEBUG_MUTATION, SetCreatedUpdatedTimeOnSave, dgraph.DebugSchema...) are defaults only
+type Config struct {
+	DebugMutation               bool // log hash on save
+	SetCreatedUpdatedTimeOnSave bool // stamp CreatedAt / UpdatedAt on save
+	DebugSchema                 bool // log skipped fields on UpdateSchema
+	AuditTrail                  bool // write audit node on save
+
+	// parse string into time.Time field, defaults to ParseISODateTime
+	TimeParser func(string) (time.Time, error)
+	// return error when a time value cannot be parsed, instead of logging and leaving the field unchanged
+	Strict bool
+
+	// spans around dgraph operations, SaveBinder and hooks, nil for none
+	Tracer lib.Tracer
+	// counters and histograms of dgraph operations and binding, nil for none
+	Metrics lib.Metrics
+
+	// deadline of each dgraph operation class, applied only when context has no deadline, 0 for none
+	QueryTimeout  time.Duration
+	MutateTimeout time.Duration
+	CommitTimeout time.Duration
+	SchemaTimeout time.Duration // schema alter
+}
+
+type configContextKey int
+
+const configKey configContextKey = iota
+
+// config from package globals
+func DefaultConfig() Config {
+	return Config{
+		DebugMutation:               DEBUG_MUTATION,
+		SetCreatedUpdatedTimeOnSave: SetCreatedUpdatedTimeOnSave,
+		DebugSchema:                 true,
+		TimeParser:                  ParseISODateTime,
+	}
+}
+
+func WithConfig(ctx context.Context, config Config) context.Context {
+	return context.WithValue(ctx, configKey, config)
+}
+
+// config attached by WithConfig, otherwise DefaultConfig
+func ConfigFrom(ctx context.Context) Config {
+	if ctx != nil {
+		if config, ok := ctx.Value(configKey).(Config); ok {
+			return config
+		}
+	}
+	return DefaultConfig()
+}
+
+// ConfigFrom with ok=false when not attached
+func LookupConfig(ctx context.Context) (Config, bool) {
+	if ctx == nil {
+		return Config{}, false
+	}
+	config, ok := ctx.Value(configKey).(Config)
+	return config, ok
+}
+
+func (c Config) tracer() lib.Tracer {
+	if c.Tracer == nil {
+		return lib.NopTracer
+	}
+	return c.Tracer
+}
+
+// start span with the configured tracer, end with lib.EndSpan
+func (c Config) StartSpan(ctx context.Context, name string, attrs ...lib.Attribute) (context.Context, lib.Span) {
+	return c.tracer().Start(ctx, name, attrs...)
+}
+
+func (c Config) MetricsRecorder() lib.Metrics {
+	if c.Metrics == nil {
+		return lib.NopMetrics
+	}
+	return c.Metrics
+}
+
+func (c Config) ParseTime(val string) (time.Time, error) {
+	if c.TimeParser == nil {
+		return ParseISODateTime(val)
+	}
+	return c.TimeParser(val)
+}
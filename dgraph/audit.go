@@ -0,0 +1,185 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// default of Config.AuditTrail, write an audit node on every create, update and destroy
+var AuditTrail = false
+
+// field names or json names never written to audit trail, example: password
+var AuditExcludedFields = []string{}
+
+const AuditTableName = "audit_logs"
+
+type auditContextKey int
+
+const auditActorKey auditContextKey = iota
+
+// exclude fields from audit per model, in addition to `audit:"-"` tag
+type BaseAuditExcludable interface {
+	AuditExcludedFields() []string
+}
+
+type AuditDiff struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type AuditLog struct {
+	UID       string    `json:"uid"`
+	Model     string    `json:"audit_model"`
+	ModelUID  string    `json:"audit_uid"`
+	Action    string    `json:"audit_action"`
+	Changes   string    `json:"audit_changes"` // json of map[string]AuditDiff
+	Actor     string    `json:"audit_actor"`
+	CreatedAt time.Time `json:"audit_at"`
+}
+
+func (a AuditLog) TableName() string {
+	return AuditTableName
+}
+
+// field diffs keyed by json name
+func (a AuditLog) Diff() (map[string]AuditDiff, error) {
+	diff := map[string]AuditDiff{}
+	if a.Changes == "" {
+		return diff, nil
+	}
+	if err := json.Unmarshal([]byte(a.Changes), &diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// set actor (user id, email...) recorded on audit nodes
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey, actor)
+}
+
+func AuditActorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(auditActorKey).(string); ok {
+		return actor
+	}
+	return ""
+}
+
+// write audit node for model within the same transaction
+// @param action create / update / destroy
+// @param binder optional, nil on destroy
+func WriteAudit(tx *DGraphTxn, ctx context.Context, action string, model interface{}, binder *ModelBinder) error {
+	hash, err := auditHash(tx, ctx, action, model, binder)
+	if err != nil || hash == nil {
+		return err
+	}
+	if _, err := tx.Save(ctx, hash, false); err != nil {
+		structuredLogging(ctx).Errorw("unable to write audit", "model", hash["audit_model"], "action", action, "error", err)
+		return err
+	}
+	return nil
+}
+
+// audit node of model, nil when audit trail is off
+func auditHash(tx *DGraphTxn, ctx context.Context, action string, model interface{}, binder *ModelBinder) (map[string]interface{}, error) {
+	if !tx.config(ctx).AuditTrail {
+		return nil, nil
+	}
+	table, ok := model.(TableNameAble)
+	if !ok {
+		return nil, fmt.Errorf("Not a table")
+	}
+
+	diff := map[string]AuditDiff{}
+	if binder != nil {
+		modelType := reflect.Indirect(reflect.ValueOf(model)).Type()
+		for name, change := range binder.ChangesSnapshot() {
+			field, ok := modelType.FieldByName(name)
+			if !ok || isAuditExcluded(model, field) {
+				continue
+			}
+			jsonName := auditJsonName(field)
+			if jsonName == "" {
+				continue
+			}
+			if IsSensitiveField(field) {
+				diff[jsonName] = AuditDiff{Old: RedactedValue, New: RedactedValue}
+				continue
+			}
+			diff[jsonName] = AuditDiff{Old: change[0], New: change[1]}
+		}
+	}
+
+	changes, err := json.Marshal(diff)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"_type":         AuditTableName,
+		"audit_model":   table.TableName(),
+		"audit_uid":     reflect.Indirect(reflect.ValueOf(model)).FieldByName("UID").Interface().(string),
+		"audit_action":  action,
+		"audit_changes": string(changes),
+		"audit_actor":   AuditActorFrom(ctx),
+		"audit_at":      time.Now().UTC(),
+	}, nil
+}
+
+// list audit trail of uid, oldest first
+func AuditHistory(tx *DGraphTxn, ctx context.Context, uid string) ([]AuditLog, error) {
+	q := `query history($uid: string) {
+		history(func: eq(audit_uid, $uid), orderasc: audit_at) {
+			uid
+			audit_model
+			audit_uid
+			audit_action
+			audit_changes
+			audit_actor
+			audit_at
+		}
+	}`
+	resp, err := tx.QueryWithVars(ctx, q, map[string]string{"$uid": uid})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		History []AuditLog `json:"history"`
+	}
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return nil, err
+	}
+	return result.History, nil
+}
+
+func isAuditExcluded(model interface{}, field reflect.StructField) bool {
+	if field.Tag.Get("audit") == "-" {
+		return true
+	}
+
+	excluded := AuditExcludedFields
+	if excludable, ok := model.(BaseAuditExcludable); ok {
+		excluded = append(excluded[:len(excluded):len(excluded)], excludable.AuditExcludedFields()...)
+	}
+	jsonName := auditJsonName(field)
+	for _, name := range excluded {
+		if strings.EqualFold(name, field.Name) || (jsonName != "" && strings.EqualFold(name, jsonName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// name part of json tag, empty when the field is not stored (no tag or "-")
+func auditJsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
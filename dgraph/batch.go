@@ -0,0 +1,161 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v2/protos/api"
+	"github.com/gobuffalo/validate"
+	"github.com/u007/gobinder/lib"
+)
+
+// save many binders in a single request, hooks run per binder as in SaveBinder
+// new models get blank nodes _:b<index>, their UID is set from the response
+// versioned updates are sent as conditional mutations of the same request, passwords with their node
+// nothing is written when any binder fails validation, timestamps are rolled back when the request is not written
+// audit nodes (need the new uids) are written together in 1 more mutation
+// on ErrStaleObject other binders may already be written, discard the transaction
+// @return validation errors by index of binders
+func SaveBinders(tx *DGraphTxn, ctx context.Context, binders ...*ModelBinder) (verrs []*validate.Errors, err error) {
+	ctx, span := tx.config(ctx).StartSpan(ctx, "binder.SaveBinders", lib.Attr("count", len(binders)))
+	defer func() {
+		lib.EndSpan(span, err)
+	}()
+
+	verrs = make([]*validate.Errors, len(binders))
+	for i := range verrs {
+		verrs[i] = validate.NewErrors()
+	}
+	if err := tx.checkWritable("save binders"); err != nil {
+		return verrs, err
+	}
+	if len(binders) == 0 {
+		return verrs, nil
+	}
+
+	plans := make([]*savePlan, len(binders))
+	written := false
+	defer func() {
+		if written {
+			return
+		}
+		for i, plan := range plans {
+			if plan != nil {
+				plan.rollback(binders[i])
+			}
+		}
+	}()
+	failed := []string{}
+	for i, binder := range binders {
+		plan, binderErrs, err := prepareSave(tx, ctx, binder)
+		if binderErrs != nil {
+			verrs[i] = binderErrs
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%d: %+v", i, err))
+			continue
+		}
+		plans[i] = plan
+	}
+	if len(failed) > 0 {
+		return verrs, fmt.Errorf("Unable to save binders, %s", strings.Join(failed, ", "))
+	}
+
+	rows := []map[string]interface{}{}
+	passwords := []*api.NQuad{}
+	blocks := []string{}
+	mutations := []*api.Mutation{}
+	for i, plan := range plans {
+		subject := plan.id
+		if plan.action == "create" {
+			subject = fmt.Sprintf("_:b%d", i)
+			plan.hash["uid"] = subject
+		}
+		sets, err := passwordNQuads(subject, plan.passwords)
+		if err != nil {
+			return verrs, fmt.Errorf("%d: %w", i, err)
+		}
+		if !plan.versioned || plan.action != "update" {
+			rows = append(rows, plan.hash)
+			passwords = append(passwords, sets...)
+			continue
+		}
+
+		if _, err := strconv.ParseUint(plan.id, 0, 64); err != nil {
+			return verrs, fmt.Errorf("%d: invalid uid %s", i, plan.id)
+		}
+		dataJson, err := json.Marshal(plan.hash)
+		if err != nil {
+			return verrs, err
+		}
+		blocks = append(blocks, fmt.Sprintf(`version%d(func: uid(%s)) @filter(%s) {
+			v%d as uid
+		}`, i, plan.id, versionFilter(plan.versionField.Tag.Get("json"), plan.version), i))
+		mutations = append(mutations, &api.Mutation{SetJson: dataJson, Set: sets, Cond: fmt.Sprintf("@if(eq(len(v%d), 1))", i)})
+	}
+
+	if len(rows) > 0 {
+		dataJson, err := json.Marshal(rows)
+		if err != nil {
+			return verrs, err
+		}
+		mutations = append([]*api.Mutation{{SetJson: dataJson, Set: passwords}}, mutations...)
+	}
+
+	req := &api.Request{Mutations: mutations}
+	if len(blocks) > 0 {
+		req.Query = fmt.Sprintf("{\n%s\n}", strings.Join(blocks, "\n"))
+	}
+	resp, err := tx.Do(ctx, req)
+	if err != nil {
+		return verrs, err
+	}
+
+	if len(blocks) > 0 {
+		result := map[string][]struct {
+			UID string `json:"uid"`
+		}{}
+		if err := json.Unmarshal(resp.Json, &result); err != nil {
+			return verrs, err
+		}
+		for i, plan := range plans {
+			if plan.versioned && plan.action == "update" && len(result[fmt.Sprintf("version%d", i)]) == 0 {
+				return verrs, fmt.Errorf("%d: %s version %d: %w", i, plan.id, plan.version, ErrStaleObject)
+			}
+		}
+	}
+	written = true
+
+	audits := []map[string]interface{}{}
+	for i, plan := range plans {
+		uid := plan.id
+		if plan.action == "create" {
+			uid = resp.Uids[fmt.Sprintf("b%d", i)]
+		}
+		if err := applySave(binders[i], plan, uid); err != nil {
+			return verrs, fmt.Errorf("%d: %w", i, err)
+		}
+		audit, err := auditHash(tx, ctx, plan.action, binders[i].Model(), binders[i])
+		if err != nil {
+			return verrs, fmt.Errorf("%d: %w", i, err)
+		}
+		if audit != nil {
+			audits = append(audits, audit)
+		}
+	}
+	if len(audits) > 0 {
+		if _, err := tx.Save(ctx, audits, false); err != nil {
+			return verrs, err
+		}
+	}
+
+	for i, plan := range plans {
+		if err := afterSave(tx, ctx, binders[i], plan); err != nil {
+			return verrs, fmt.Errorf("%d: %w", i, err)
+		}
+	}
+	return verrs, nil
+}
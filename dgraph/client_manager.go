@@ -0,0 +1,360 @@
+package dgraph
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/dgo/v2"
+	"github.com/dgraph-io/dgo/v2/protos/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type ClientManagerOptions struct {
+	Addresses []string //alpha host:port
+
+	TLS        *tls.Config //overrides CACertFile, CertFile and KeyFile
+	CACertFile string
+	CertFile   string //client certificate, for mutual tls
+	KeyFile    string
+	ServerName string
+
+	// acl login, empty to skip
+	Username        string
+	Password        string
+	RefreshInterval time.Duration //re-login every interval, 0 to disable
+
+	HealthInterval time.Duration //background health check and reconnect, 0 to disable
+	DialTimeout    time.Duration
+
+	// custom dialer, example: bufconn in tests
+	Dialer      func(ctx context.Context, address string) (net.Conn, error)
+	DialOptions []grpc.DialOption
+}
+
+// connections to a list of alphas, transactions are spread round robin across healthy endpoints
+type ClientManager struct {
+	options   ClientManagerOptions
+	dialOpts  []grpc.DialOption
+	endpoints []*clientEndpoint
+	next      uint32
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+type clientEndpoint struct {
+	lock    sync.RWMutex
+	address string
+	conn    *grpc.ClientConn
+	client  *dgo.Dgraph
+	err     error //last connect or health check error, nil when healthy
+	// last failed login, kept until a login succeeds even when version checks pass
+	loginErr error
+}
+
+func (e *clientEndpoint) healthy() bool {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	return e.client != nil && e.err == nil && e.loginErr == nil
+}
+
+// dial every address and login, endpoints unreachable at start are retried by HealthCheck
+func NewClientManager(ctx context.Context, options ClientManagerOptions) (*ClientManager, error) {
+	if len(options.Addresses) == 0 {
+		return nil, fmt.Errorf("Dgraph addresses required")
+	}
+	if options.DialTimeout == 0 {
+		options.DialTimeout = 10 * time.Second
+	}
+
+	dialOpts, err := clientDialOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &ClientManager{options: options, dialOpts: dialOpts, done: make(chan struct{})}
+	m.endpoints = make([]*clientEndpoint, len(options.Addresses))
+	//dial concurrently, startup waits for the slowest endpoint rather than the sum of dial timeouts
+	var wg sync.WaitGroup
+	for i, address := range options.Addresses {
+		endpoint := &clientEndpoint{address: address}
+		m.endpoints[i] = endpoint
+		wg.Add(1)
+		go func(endpoint *clientEndpoint) {
+			defer wg.Done()
+			if err := m.connect(ctx, endpoint); err != nil {
+				structuredLogging(ctx).Warnw("dgraph endpoint unavailable", "address", endpoint.address, "error", err)
+			}
+		}(endpoint)
+	}
+	wg.Wait()
+	if !m.anyHealthy() {
+		m.Close()
+		return nil, fmt.Errorf("Unable to connect to %s: %w", strings.Join(options.Addresses, ","), ErrNoHealthyEndpoint)
+	}
+
+	if options.HealthInterval > 0 {
+		m.every(options.HealthInterval, func(ctx context.Context) { m.HealthCheck(ctx) })
+	}
+	if options.RefreshInterval > 0 && options.Username != "" {
+		m.every(options.RefreshInterval, func(ctx context.Context) { m.RefreshLogin(ctx) })
+	}
+	return m, nil
+}
+
+func clientDialOptions(options ClientManagerOptions) ([]grpc.DialOption, error) {
+	dialOpts := []grpc.DialOption{}
+	tlsConfig := options.TLS
+	if tlsConfig == nil && (options.CACertFile != "" || options.CertFile != "") {
+		tlsConfig = &tls.Config{ServerName: options.ServerName}
+		if options.CACertFile != "" {
+			ca, err := ioutil.ReadFile(options.CACertFile)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to read ca cert: %+v", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("Invalid ca cert: %s", options.CACertFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if options.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to load client cert: %+v", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+	if tlsConfig != nil {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	}
+
+	if options.Dialer != nil {
+		dialOpts = append(dialOpts, grpc.WithContextDialer(options.Dialer))
+	}
+	return append(dialOpts, options.DialOptions...), nil
+}
+
+// (re)dial endpoint, login and check version
+func (m *ClientManager) connect(ctx context.Context, endpoint *clientEndpoint) error {
+	dialCtx, cancel := context.WithTimeout(ctx, m.options.DialTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(dialCtx, endpoint.address, append(m.dialOpts, grpc.WithBlock())...)
+	if err == nil {
+		client := dgo.NewDgraphClient(api.NewDgraphClient(conn))
+		err = m.check(ctx, conn, client)
+		if err != nil {
+			conn.Close()
+		} else {
+			endpoint.lock.Lock()
+			old := endpoint.conn
+			endpoint.conn, endpoint.client, endpoint.err, endpoint.loginErr = conn, client, nil, nil
+			endpoint.lock.Unlock()
+			if old != nil {
+				old.Close()
+			}
+			return nil
+		}
+	}
+
+	endpoint.lock.Lock()
+	endpoint.err = err
+	endpoint.lock.Unlock()
+	return err
+}
+
+func (m *ClientManager) check(ctx context.Context, conn *grpc.ClientConn, client *dgo.Dgraph) error {
+	if m.options.Username != "" {
+		if err := client.Login(ctx, m.options.Username, m.options.Password); err != nil {
+			return fmt.Errorf("Login failed: %+v", err)
+		}
+	}
+	_, err := api.NewDgraphClient(conn).CheckVersion(ctx, &api.Check{})
+	return err
+}
+
+// next healthy client, round robin
+func (m *ClientManager) Client() (*dgo.Dgraph, error) {
+	count := len(m.endpoints)
+	start := atomic.AddUint32(&m.next, 1)
+	for i := 0; i < count; i++ {
+		endpoint := m.endpoints[(int(start)+i)%count]
+		endpoint.lock.RLock()
+		client, err, loginErr := endpoint.client, endpoint.err, endpoint.loginErr
+		endpoint.lock.RUnlock()
+		if client != nil && err == nil && loginErr == nil {
+			return client, nil
+		}
+	}
+	return nil, ErrNoHealthyEndpoint
+}
+
+func (m *ClientManager) NewTxn() (*DGraphTxn, error) {
+	client, err := m.Client()
+	if err != nil {
+		return nil, err
+	}
+	return NewDGraphTxnFromClient(client), nil
+}
+
+func (m *ClientManager) NewReadOnlyTxn() (*DGraphTxn, error) {
+	client, err := m.Client()
+	if err != nil {
+		return nil, err
+	}
+	return NewReadOnlyDGraphTxnFromClient(client), nil
+}
+
+// RunInTxn on the next healthy client
+func (m *ClientManager) RunInTxn(ctx context.Context, fn func(tx *DGraphTxn) error, opts ...TxnOptions) error {
+	client, err := m.Client()
+	if err != nil {
+		return err
+	}
+	return RunInTxn(ctx, client, fn, opts...)
+}
+
+// check version of every endpoint, reconnect failed ones
+// @return error of each address, nil when healthy
+func (m *ClientManager) Health(ctx context.Context) map[string]error {
+	result := make(map[string]error, len(m.endpoints))
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, endpoint := range m.endpoints {
+		wg.Add(1)
+		go func(endpoint *clientEndpoint) {
+			defer wg.Done()
+			err := m.checkEndpoint(ctx, endpoint)
+			lock.Lock()
+			result[endpoint.address] = err
+			lock.Unlock()
+		}(endpoint)
+	}
+	wg.Wait()
+	return result
+}
+
+// nil when at least 1 endpoint is healthy
+func (m *ClientManager) HealthCheck(ctx context.Context) error {
+	failed := []string{}
+	for address, err := range m.Health(ctx) {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %+v", address, err))
+		}
+	}
+	if len(failed) == len(m.endpoints) {
+		return fmt.Errorf("%s: %w", strings.Join(failed, ", "), ErrNoHealthyEndpoint)
+	}
+	return nil
+}
+
+func (m *ClientManager) checkEndpoint(ctx context.Context, endpoint *clientEndpoint) error {
+	endpoint.lock.RLock()
+	conn, client, loginErr := endpoint.conn, endpoint.client, endpoint.loginErr
+	endpoint.lock.RUnlock()
+
+	if conn != nil {
+		_, err := api.NewDgraphClient(conn).CheckVersion(ctx, &api.Check{})
+		if err == nil {
+			if loginErr != nil {
+				loginErr = client.Login(ctx, m.options.Username, m.options.Password)
+			}
+			endpoint.lock.Lock()
+			endpoint.err, endpoint.loginErr = nil, loginErr
+			endpoint.lock.Unlock()
+			if loginErr != nil {
+				return fmt.Errorf("Login failed: %+v", loginErr)
+			}
+			return nil
+		}
+		structuredLogging(ctx).Warnw("dgraph endpoint unhealthy, reconnecting", "address", endpoint.address, "error", err)
+		endpoint.lock.Lock()
+		endpoint.err = err
+		endpoint.lock.Unlock()
+	}
+	return m.connect(ctx, endpoint)
+}
+
+// login again on every healthy endpoint, before the access token expires
+func (m *ClientManager) RefreshLogin(ctx context.Context) error {
+	if m.options.Username == "" {
+		return nil
+	}
+	var lastErr error
+	for _, endpoint := range m.endpoints {
+		if !endpoint.healthy() {
+			continue
+		}
+		endpoint.lock.RLock()
+		client := endpoint.client
+		endpoint.lock.RUnlock()
+		if err := client.Login(ctx, m.options.Username, m.options.Password); err != nil {
+			structuredLogging(ctx).Errorw("dgraph login refresh failed", "address", endpoint.address, "error", err)
+			endpoint.lock.Lock()
+			endpoint.loginErr = err
+			endpoint.lock.Unlock()
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (m *ClientManager) every(interval time.Duration, fn func(ctx context.Context)) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				fn(ctx)
+				cancel()
+			}
+		}
+	}()
+}
+
+func (m *ClientManager) anyHealthy() bool {
+	for _, endpoint := range m.endpoints {
+		if endpoint.healthy() {
+			return true
+		}
+	}
+	return false
+}
+
+// stop background checks and close all connections
+func (m *ClientManager) Close() error {
+	var lastErr error
+	m.closeOnce.Do(func() {
+		close(m.done)
+		m.wg.Wait()
+		for _, endpoint := range m.endpoints {
+			endpoint.lock.Lock()
+			if endpoint.conn != nil {
+				if err := endpoint.conn.Close(); err != nil {
+					lastErr = err
+				}
+			}
+			endpoint.conn, endpoint.client = nil, nil
+			endpoint.lock.Unlock()
+		}
+	})
+	return lastErr
+}
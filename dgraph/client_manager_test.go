@@ -0,0 +1,243 @@
+package dgraph_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgo/v2/protos/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/u007/gobinder/dgraph"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// in-process alpha stand-in, only version check and login are served
+type fakeAlpha struct {
+	api.DgraphServer
+	down        int32
+	rejectLogin int32
+	logins      int32
+}
+
+func (f *fakeAlpha) CheckVersion(ctx context.Context, check *api.Check) (*api.Version, error) {
+	if atomic.LoadInt32(&f.down) == 1 {
+		return nil, errors.New("alpha down")
+	}
+	return &api.Version{Tag: "fake"}, nil
+}
+
+func (f *fakeAlpha) Login(ctx context.Context, req *api.LoginRequest) (*api.Response, error) {
+	atomic.AddInt32(&f.logins, 1)
+	if atomic.LoadInt32(&f.rejectLogin) == 1 {
+		return nil, errors.New("invalid credentials")
+	}
+	jwt := &api.Jwt{AccessJwt: "access", RefreshJwt: "refresh"}
+	data, err := jwt.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &api.Response{Json: data}, nil
+}
+
+func startFakeAlpha(t *testing.T, opts ...grpc.ServerOption) (*fakeAlpha, *bufconn.Listener) {
+	alpha := &fakeAlpha{}
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(opts...)
+	api.RegisterDgraphServer(server, alpha)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return alpha, listener
+}
+
+func TestClientManager(t *testing.T) {
+	alpha1, listener1 := startFakeAlpha(t)
+	alpha2, listener2 := startFakeAlpha(t)
+	listeners := map[string]*bufconn.Listener{"alpha1:9080": listener1, "alpha2:9080": listener2}
+
+	ctx := context.Background()
+	manager, err := dgraph.NewClientManager(ctx, dgraph.ClientManagerOptions{
+		Addresses: []string{"alpha1:9080", "alpha2:9080"},
+		Username:  "groot",
+		Password:  "password",
+		Dialer: func(ctx context.Context, address string) (net.Conn, error) {
+			return listeners[address].Dial()
+		},
+		DialTimeout: time.Second,
+	})
+	assert.NoError(t, err)
+	defer manager.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&alpha1.logins))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&alpha2.logins))
+
+	first, err := manager.Client()
+	assert.NoError(t, err)
+	second, err := manager.Client()
+	assert.NoError(t, err)
+	assert.True(t, first != second, "round robin across endpoints")
+
+	atomic.StoreInt32(&alpha2.down, 1)
+	health := manager.Health(ctx)
+	assert.NoError(t, health["alpha1:9080"])
+	assert.Error(t, health["alpha2:9080"])
+	assert.NoError(t, manager.HealthCheck(ctx))
+	healthy, err := manager.Client()
+	assert.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		client, err := manager.Client()
+		assert.NoError(t, err)
+		assert.True(t, client == healthy, "unhealthy endpoint skipped")
+	}
+
+	atomic.StoreInt32(&alpha1.down, 1)
+	err = manager.HealthCheck(ctx)
+	assert.True(t, errors.Is(err, dgraph.ErrNoHealthyEndpoint), "%+v", err)
+	_, err = manager.NewTxn()
+	assert.True(t, errors.Is(err, dgraph.ErrNoHealthyEndpoint), "%+v", err)
+
+	//recovered alphas are reconnected and logged in again
+	atomic.StoreInt32(&alpha1.down, 0)
+	atomic.StoreInt32(&alpha2.down, 0)
+	assert.NoError(t, manager.HealthCheck(ctx))
+	assert.True(t, atomic.LoadInt32(&alpha1.logins) > 1)
+	tx, err := manager.NewReadOnlyTxn()
+	assert.NoError(t, err)
+	assert.True(t, tx.ReadOnly)
+
+	assert.NoError(t, manager.RefreshLogin(ctx))
+
+	//failed login keeps endpoint unhealthy until a login succeeds
+	atomic.StoreInt32(&alpha1.rejectLogin, 1)
+	atomic.StoreInt32(&alpha2.rejectLogin, 1)
+	assert.Error(t, manager.RefreshLogin(ctx))
+	err = manager.HealthCheck(ctx)
+	assert.True(t, errors.Is(err, dgraph.ErrNoHealthyEndpoint), "%+v", err)
+	_, err = manager.Client()
+	assert.True(t, errors.Is(err, dgraph.ErrNoHealthyEndpoint), "%+v", err)
+	atomic.StoreInt32(&alpha1.rejectLogin, 0)
+	atomic.StoreInt32(&alpha2.rejectLogin, 0)
+	assert.NoError(t, manager.HealthCheck(ctx))
+	_, err = manager.Client()
+	assert.NoError(t, err)
+
+	assert.NoError(t, manager.Close())
+	_, err = manager.Client()
+	assert.True(t, errors.Is(err, dgraph.ErrNoHealthyEndpoint))
+}
+
+func TestClientManagerConcurrentDial(t *testing.T) {
+	_, listener := startFakeAlpha(t)
+	timeout := 500 * time.Millisecond
+
+	//unreachable endpoints hang until the dial timeout, dialed one after another startup takes 2 timeouts
+	start := time.Now()
+	manager, err := dgraph.NewClientManager(context.Background(), dgraph.ClientManagerOptions{
+		Addresses: []string{"dead1:9080", "alpha1:9080", "dead2:9080"},
+		Dialer: func(ctx context.Context, address string) (net.Conn, error) {
+			if address == "alpha1:9080" {
+				return listener.Dial()
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		DialTimeout: timeout,
+	})
+	elapsed := time.Since(start)
+	assert.NoError(t, err)
+	defer manager.Close()
+	assert.True(t, elapsed < 2*timeout, "endpoints dialed concurrently, took %s", elapsed)
+
+	health := manager.Health(context.Background())
+	assert.Len(t, health, 3)
+	for i := 0; i < 3; i++ {
+		_, err := manager.Client()
+		assert.NoError(t, err)
+	}
+}
+
+// self-signed certificate for serverName, as tls certificate and pem
+func selfSignedCert(t *testing.T, serverName string) (tls.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: serverName},
+		DNSNames:              []string{serverName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	cert, err := tls.X509KeyPair(certPem, keyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, certPem
+}
+
+func TestClientManagerTLS(t *testing.T) {
+	cert, certPem := selfSignedCert(t, "alpha.test")
+	_, listener := startFakeAlpha(t, grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	dialer := func(ctx context.Context, address string) (net.Conn, error) {
+		return listener.Dial()
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	assert.NoError(t, ioutil.WriteFile(caFile, certPem, 0600))
+
+	ctx := context.Background()
+	manager, err := dgraph.NewClientManager(ctx, dgraph.ClientManagerOptions{
+		Addresses:   []string{"alpha.test:9080"},
+		CACertFile:  caFile,
+		ServerName:  "alpha.test",
+		Dialer:      dialer,
+		DialTimeout: time.Second,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, manager.HealthCheck(ctx))
+	assert.NoError(t, manager.Close())
+
+	//untrusted server certificate
+	_, err = dgraph.NewClientManager(ctx, dgraph.ClientManagerOptions{
+		Addresses:   []string{"alpha.test:9080"},
+		TLS:         &tls.Config{ServerName: "alpha.test", RootCAs: x509.NewCertPool()},
+		Dialer:      dialer,
+		DialTimeout: time.Second,
+	})
+	assert.True(t, errors.Is(err, dgraph.ErrNoHealthyEndpoint), "%+v", err)
+
+	//plaintext client against tls server
+	_, err = dgraph.NewClientManager(ctx, dgraph.ClientManagerOptions{
+		Addresses:   []string{"alpha.test:9080"},
+		Dialer:      dialer,
+		DialTimeout: time.Second,
+	})
+	assert.True(t, errors.Is(err, dgraph.ErrNoHealthyEndpoint), "%+v", err)
+}
@@ -8,19 +8,57 @@ import (
 	"strings"
 	"time"
 
-	"github.com/dgraph-io/dgo"
-	"github.com/dgraph-io/dgo/protos/api"
-	"github.com/dgraph-io/dgo/y"
+	"github.com/dgraph-io/dgo/v2"
+	"github.com/dgraph-io/dgo/v2/protos/api"
 	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/u007/gobinder/lib"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
 
+// default of Config.DebugSchema
+//
+// Deprecated: set Config.DebugSchema on DGraphTxn.Config or with WithConfig instead
 var DebugSchema = true
 
 type DGraphTxn struct {
 	Tx     *dgo.Txn
 	Client *dgo.Dgraph
+
+	Config *Config //nil to use config of context, or package defaults
+
+	ReadOnly   bool //mutations fail with ErrReadOnlyTxn, commit only discards
+	BestEffort bool
+}
+
+// config of this transaction, then context, then package defaults
+func (d *DGraphTxn) config(ctx context.Context) Config {
+	if d != nil && d.Config != nil {
+		return *d.Config
+	}
+	if config, ok := LookupConfig(ctx); ok {
+		return config
+	}
+	return defaultConfig()
+}
+
+func defaultConfig() Config {
+	config := DefaultConfig()
+	config.DebugSchema = DebugSchema
+	config.AuditTrail = AuditTrail
+	config.QueryTimeout = QueryTimeout
+	config.MutateTimeout = MutateTimeout
+	config.CommitTimeout = CommitTimeout
+	config.SchemaTimeout = SchemaTimeout
+	return config
+}
+
+// context carrying the transaction config, for binder and BindFieldValue
+func (d *DGraphTxn) withConfig(ctx context.Context) context.Context {
+	if d == nil || d.Config == nil {
+		return ctx
+	}
+	return WithConfig(ctx, *d.Config)
 }
 
 func NewDGraphTxn(connection *grpc.ClientConn) *DGraphTxn {
@@ -35,21 +73,47 @@ func (d *DGraphTxn) Schema(ctx context.Context, schema string) error {
 	op := &api.Operation{
 		Schema: schema,
 	}
+	ctx, cancel, timeout := withTimeout(ctx, d.config(ctx).SchemaTimeout)
+	defer cancel()
 	err := d.Client.Alter(ctx, op)
-	return err
+	return timeoutError(ctx, "schema", timeout, err)
 }
 
 func (d *DGraphTxn) Discard(ctx context.Context) error {
 	return d.Tx.Discard(ctx)
 }
 
-func (d *DGraphTxn) Commit(ctx context.Context) error {
-	return d.Tx.Commit(ctx)
+func (d *DGraphTxn) Commit(ctx context.Context) (err error) {
+	config := d.config(ctx)
+	ctx, span := config.StartSpan(ctx, "dgraph.Commit")
+	defer func(start time.Time) {
+		observeOperation(config, "commit", start, err, nil)
+		lib.EndSpan(span, err)
+	}(time.Now())
+	if d.ReadOnly {
+		return d.Tx.Discard(ctx)
+	}
+	ctx, cancel, timeout := withTimeout(ctx, config.CommitTimeout)
+	defer cancel()
+	return timeoutError(ctx, "commit", timeout, d.Tx.Commit(ctx))
 }
 
-func (d *DGraphTxn) QueryWithVars(ctx context.Context, q string, qVars map[string]string) (*api.Response, error) {
+func (d *DGraphTxn) QueryWithVars(ctx context.Context, q string, qVars map[string]string) (resp *api.Response, err error) {
 	// logging(ctx).Debugf("vars, Q:%#v | %#v", q, qVars)
-	return d.Tx.QueryWithVars(ctx, q, qVars)
+	config := d.config(ctx)
+	ctx, span := config.StartSpan(ctx, "dgraph.QueryWithVars", lib.Attr("vars", len(qVars)))
+	defer func(start time.Time) {
+		var latency *api.Latency
+		if resp != nil {
+			latency = resp.Latency
+		}
+		observeOperation(config, "query", start, err, latency)
+		lib.EndSpan(span, err)
+	}(time.Now())
+	ctx, cancel, timeout := withTimeout(ctx, config.QueryTimeout)
+	defer cancel()
+	resp, err = d.Tx.QueryWithVars(ctx, q, qVars)
+	return resp, timeoutError(ctx, "query", timeout, err)
 }
 
 func DeleteRelationField(tx *DGraphTxn, ctx context.Context, model interface{}, fieldName string, deleteRelation bool) error {
@@ -76,21 +140,22 @@ func DeleteRelationField(tx *DGraphTxn, ctx context.Context, model interface{},
 
 	childField := reflect.Indirect(queryModel.Elem().FieldByName(fieldName))
 	// logging(ctx).Debugf("child found: %s: %#v | %#v", fieldName, queryModel.Elem(), childField)
-	logging(ctx).Debugf("child: %s (%s) = %#v", fieldName, childField.Kind(), childField)
+	log := structuredLogging(ctx).With("model", reflect.TypeOf(model).String(), "uid", id, "predicate", dbName)
+	log.Debugw("child", "kind", childField.Kind())
 	//ensure is not nil
 	if childField.IsValid() {
 		childs := childField.Interface()
-		logging(ctx).Debugf("child: %s = %#v (%s)", fieldName, childs, childField.Kind())
+		log.Debugw("child", "kind", childField.Kind(), "value", Redacted(childs))
 		if childField.Kind() == reflect.Slice {
 			for i := 0; i < childField.Len(); i++ {
 				row := childField.Index(i)
-				logging(ctx).Debugf("Deleting multiple relation: %s %#v", dbName, row.Interface())
+				log.Debugw("deleting multiple relation", "action", "destroy", "child", Redacted(row.Interface()))
 				if _, err := Destroy(tx, ctx, row.Addr().Interface()); err != nil {
 					return err
 				}
 			}
 		} else if childField.Kind() == reflect.Struct {
-			logging(ctx).Debugf("Deleting relation: %s %#v", dbName, childField)
+			log.Debugw("deleting relation", "action", "destroy", "child", Redacted(childField.Interface()))
 			if _, err := Destroy(tx, ctx, childField.Addr().Interface()); err != nil {
 				return err
 			}
@@ -102,16 +167,16 @@ func DeleteRelationField(tx *DGraphTxn, ctx context.Context, model interface{},
 	}
 
 	if _, err := tx.MutateDeleteField(ctx, id, dbName, nil, false); err != nil {
-		logging(ctx).Errorf("unable to delete field: %+v", err)
+		log.Errorw("unable to delete field", "error", err)
 		return err
 	}
 	return nil
 }
 
-func (d *DGraphTxn) Save(ctx context.Context, data interface{}, commit bool) (*api.Assigned, error) {
+func (d *DGraphTxn) Save(ctx context.Context, data interface{}, commit bool) (*api.Response, error) {
 	dataJson, err := json.Marshal(data)
 	if err != nil {
-		dummy := api.Assigned{}
+		dummy := api.Response{}
 		return &dummy, err
 	}
 
@@ -122,15 +187,15 @@ func (d *DGraphTxn) Save(ctx context.Context, data interface{}, commit bool) (*a
 }
 
 // Delete an object by id
-func (d *DGraphTxn) DeleteByUID(ctx context.Context, uid string, commit bool) (*api.Assigned, error) {
+func (d *DGraphTxn) DeleteByUID(ctx context.Context, uid string, commit bool) (*api.Response, error) {
 	if uid == "" {
-		return &api.Assigned{}, fmt.Errorf("invalid uid")
+		return &api.Response{}, fmt.Errorf("invalid uid")
 	}
 
 	hash := map[string]string{"uid": uid}
 	pb, err := json.Marshal(hash)
 	if err != nil {
-		return &api.Assigned{}, err
+		return &api.Response{}, err
 	}
 
 	return d.Mutate(ctx, &api.Mutation{
@@ -140,7 +205,7 @@ func (d *DGraphTxn) DeleteByUID(ctx context.Context, uid string, commit bool) (*
 }
 
 // https://docs.dgraph.io/mutations/#triples
-func (d *DGraphTxn) MutateSet(ctx context.Context, sets []*api.NQuad, commit bool) (*api.Assigned, error) {
+func (d *DGraphTxn) MutateSet(ctx context.Context, sets []*api.NQuad, commit bool) (*api.Response, error) {
 	return d.Mutate(ctx, &api.Mutation{
 		Set:       sets,
 		CommitNow: commit,
@@ -148,7 +213,7 @@ func (d *DGraphTxn) MutateSet(ctx context.Context, sets []*api.NQuad, commit boo
 }
 
 //create relation from "id" by relation(name/predicate) to "relatedID"
-func (d *DGraphTxn) Associate(ctx context.Context, id string, relation string, relatedID string, commit bool) (*api.Assigned, error) {
+func (d *DGraphTxn) Associate(ctx context.Context, id string, relation string, relatedID string, commit bool) (*api.Response, error) {
 	val := &api.NQuad{Subject: id, Predicate: relation, ObjectId: relatedID}
 
 	sets := []*api.NQuad{
@@ -162,16 +227,24 @@ func (d *DGraphTxn) Associate(ctx context.Context, id string, relation string, r
 }
 
 // Set value for a predicate by object ID
-func (d *DGraphTxn) MutateField(ctx context.Context, id string, fieldName string, value interface{}, commit bool) (*api.Assigned, error) {
-	apiVal, err := parseAsApiValue(value)
-	if err != nil {
-		return &api.Assigned{}, err
+// slices (except []byte) are written as list predicate, one nquad per element
+// LocalizedString is written as predicate@lang, see MutateFieldLang
+func (d *DGraphTxn) MutateField(ctx context.Context, id string, fieldName string, value interface{}, commit bool) (*api.Response, error) {
+	if localized, ok := value.(LocalizedString); ok {
+		return d.MutateFieldLang(ctx, id, fieldName, "", localized, commit)
+	}
+	values := []interface{}{value}
+	if isListValue(value) {
+		values = listValues(value)
 	}
 
-	val := &api.NQuad{Subject: id, Predicate: fieldName, ObjectValue: apiVal}
-
-	sets := []*api.NQuad{
-		val,
+	sets := []*api.NQuad{}
+	for _, value := range values {
+		apiVal, err := parseAsApiValue(value)
+		if err != nil {
+			return &api.Response{}, err
+		}
+		sets = append(sets, &api.NQuad{Subject: id, Predicate: fieldName, ObjectValue: apiVal})
 	}
 
 	return d.Mutate(ctx, &api.Mutation{
@@ -180,56 +253,12 @@ func (d *DGraphTxn) MutateField(ctx context.Context, id string, fieldName string
 	})
 }
 
-func parseAsApiValue(value interface{}) (*api.Value, error) {
-	valType := reflect.TypeOf(value)
-	if valType.Kind() == reflect.Slice {
-		valType = valType.Elem()
-		switch valType.Name() {
-		case "uint8":
-			return &api.Value{Val: &api.Value_BytesVal{value.([]byte)}}, nil
-		}
-
-		return &api.Value{}, fmt.Errorf("Unsupported slice %s", valType.Name())
-	}
-	//get value instead of ptr
-	if valType.Kind() == reflect.Ptr {
-		valType = valType.Elem()
-		value = reflect.ValueOf(value).Elem().Interface()
-	}
-
-	switch valType.Name() {
-	case "string":
-		return &api.Value{Val: &api.Value_DefaultVal{value.(string)}}, nil
-	case "bool":
-		return &api.Value{Val: &api.Value_BoolVal{value.(bool)}}, nil
-	case "int":
-		return &api.Value{Val: &api.Value_IntVal{int64(value.(int))}}, nil
-	case "int8":
-		return &api.Value{Val: &api.Value_IntVal{int64(value.(int8))}}, nil
-	case "int16":
-		return &api.Value{Val: &api.Value_IntVal{int64(value.(int16))}}, nil
-	case "int32":
-		return &api.Value{Val: &api.Value_IntVal{int64(value.(int32))}}, nil
-	case "int64":
-		return &api.Value{Val: &api.Value_IntVal{value.(int64)}}, nil
-	case "float32":
-		return &api.Value{Val: &api.Value_DoubleVal{float64(value.(float32))}}, nil
-	case "float64":
-		return &api.Value{Val: &api.Value_DoubleVal{value.(float64)}}, nil
-	case "Time":
-		theTime := value.(time.Time)
-		// return types.Convert(value, types.DateTimeID), nil
-		return &api.Value{Val: &api.Value_DefaultVal{theTime.Format(time.RFC3339)}}, nil
-	}
-	return &api.Value{}, fmt.Errorf("Unsupported type %s", reflect.TypeOf(value).Name())
-}
-
 // ctx
 //	id object id
 //	predicate
 //	value (nil / api.Value optional) - nil means *
 //	commit bool
-func (d *DGraphTxn) MutateDeleteField(ctx context.Context, id string, field string, value *api.Value, commit bool) (*api.Assigned, error) {
+func (d *DGraphTxn) MutateDeleteField(ctx context.Context, id string, field string, value *api.Value, commit bool) (*api.Response, error) {
 	val := &api.NQuad{Subject: id, Predicate: field}
 	// val.ObjectValue =  &api.Value{Val: &api.Value_StrVal{StrVal: "*"}}
 	if value != nil {
@@ -254,33 +283,72 @@ delete {
      <0xf11168064b01135b> <died> "1998" .
   }
 */
-func (d *DGraphTxn) MutateDelete(ctx context.Context, raw string, commit bool) (*api.Assigned, error) {
+func (d *DGraphTxn) MutateDelete(ctx context.Context, raw string, commit bool) (*api.Response, error) {
 	return d.Mutate(ctx, &api.Mutation{
 		DeleteJson: []byte(raw),
 		CommitNow:  commit,
 	})
 }
 
-func (d *DGraphTxn) Mutate(ctx context.Context, mu *api.Mutation) (*api.Assigned, error) {
-	assigned, err := d.Tx.Mutate(ctx, mu)
-	return assigned, err
+func (d *DGraphTxn) Mutate(ctx context.Context, mu *api.Mutation) (resp *api.Response, err error) {
+	if err := d.checkWritable("mutate"); err != nil {
+		return nil, err
+	}
+	config := d.config(ctx)
+	ctx, span := config.StartSpan(ctx, "dgraph.Mutate",
+		lib.Attr("nquads", len(mu.Set)+len(mu.Del)), lib.Attr("commit_now", mu.CommitNow))
+	defer func(start time.Time) {
+		var latency *api.Latency
+		if resp != nil {
+			latency = resp.Latency
+		}
+		observeOperation(config, "mutate", start, err, latency)
+		lib.EndSpan(span, err)
+	}(time.Now())
+	ctx, cancel, timeout := withTimeout(ctx, config.MutateTimeout)
+	defer cancel()
+	resp, err = d.Tx.Mutate(ctx, mu)
+	return resp, timeoutError(ctx, "mutate", timeout, err)
 }
 
-func (d *DGraphTxn) Transact(ctx context.Context) error {
-	trial, maxTrial := 1, 3
-	for {
-		err := d.Tx.Commit(ctx)
-		if err == y.ErrAborted {
-			// Retry or handle error
-			trial++
-			if trial > maxTrial {
-				return err
-			}
-		} else {
-			return err
+// send query and (conditional) mutations as a single request
+func (d *DGraphTxn) Do(ctx context.Context, req *api.Request) (resp *api.Response, err error) {
+	if len(req.Mutations) > 0 {
+		if err := d.checkWritable("do"); err != nil {
+			return nil, err
 		}
 	}
-	return nil
+	nquads := 0
+	for _, mu := range req.Mutations {
+		nquads += len(mu.Set) + len(mu.Del)
+	}
+	config := d.config(ctx)
+	ctx, span := config.StartSpan(ctx, "dgraph.Do",
+		lib.Attr("mutations", len(req.Mutations)), lib.Attr("nquads", nquads), lib.Attr("commit_now", req.CommitNow))
+	defer func(start time.Time) {
+		var latency *api.Latency
+		if resp != nil {
+			latency = resp.Latency
+		}
+		observeOperation(config, "do", start, err, latency)
+		lib.EndSpan(span, err)
+	}(time.Now())
+	op, timeout := "query", config.QueryTimeout
+	if len(req.Mutations) > 0 {
+		op, timeout = "mutate", config.MutateTimeout
+	}
+	ctx, cancel, timeout := withTimeout(ctx, timeout)
+	defer cancel()
+	resp, err = d.Tx.Do(ctx, req)
+	return resp, timeoutError(ctx, op, timeout, err)
+}
+
+// commit the transaction
+// an aborted transaction can not be committed again, use RunInTxn to rerun the whole transaction
+func (d *DGraphTxn) Transact(ctx context.Context) (err error) {
+	ctx, span := d.config(ctx).StartSpan(ctx, "dgraph.Transact")
+	defer func() { lib.EndSpan(span, err) }()
+	return d.Commit(ctx)
 }
 
 func UpdateSchema(tx *DGraphTxn, ctx context.Context, model interface{}, commit bool) error {
@@ -300,13 +368,23 @@ func UpdateSchema(tx *DGraphTxn, ctx context.Context, model interface{}, commit
 		modelType = reflect.TypeOf(model)
 	}
 	record := reflect.New(modelType).Elem()
+	config := tx.config(ctx)
 
 	for i := 0; i < modelType.NumField(); i++ {
 		field := modelType.Field(i)
 		fieldName := field.Name
 		fieldType := field.Type
-		if fieldName == "UID" {
-			continue //skip
+		if fieldName == "UID" || isFacetField(field) {
+			continue //skip, facets belong to edges
+		}
+
+		if isPasswordField(field) {
+			//password values are never backfilled, only the predicate type is declared
+			predicate := strings.Split(field.Tag.Get("json"), ",")[0]
+			if err := tx.Schema(ctx, fmt.Sprintf("%s: password .", predicate)); err != nil {
+				return fmt.Errorf("Unable to declare password %s of %s, err: %+v", fieldName, tableName, err)
+			}
+			continue
 		}
 
 		if fieldType.Kind() == reflect.Ptr {
@@ -314,19 +392,26 @@ func UpdateSchema(tx *DGraphTxn, ctx context.Context, model interface{}, commit
 		}
 
 		if fieldType.Kind() == reflect.Slice {
-			if DebugSchema {
+			if config.DebugSchema {
 				logging(ctx).Debugf("Skipping slice %s", fieldName)
 			}
 			continue // skip
 		}
 
 		if fieldType.Kind() == reflect.Struct && fieldType.String() != "time.Time" {
-			if DebugSchema {
+			if config.DebugSchema {
 				logging(ctx).Debugf("Skipping struct %s", fieldName)
 			}
 			continue //skip
 		}
 
+		if fieldType.Kind() == reflect.Map {
+			if config.DebugSchema {
+				logging(ctx).Debugf("Skipping map %s", fieldName)
+			}
+			continue //skip, example: LocalizedString
+		}
+
 		dbFieldName := field.Tag.Get("json")
 		if dbFieldName == "" {
 			return fmt.Errorf("Missing json tag for field %s of %s", fieldName, tableName)
@@ -334,6 +419,9 @@ func UpdateSchema(tx *DGraphTxn, ctx context.Context, model interface{}, commit
 
 		// logging(ctx).Debugf("field: %s - %s", fieldName, dbFieldName)
 		for {
+			if err := contextError(ctx, "schema"); err != nil {
+				return fmt.Errorf("Update schema %s of %s interrupted: %w", fieldName, tableName, err)
+			}
 
 			slice := reflect.MakeSlice(reflect.SliceOf(modelType), 0, 0)
 			rowsAddr := reflect.New(slice.Type())
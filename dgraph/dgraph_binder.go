@@ -2,20 +2,29 @@ package dgraph
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"time"
 
 	_ "github.com/u007/gobinder"
 
-	"github.com/fatih/structs"
+	"github.com/dgraph-io/dgo/v2/protos/api"
 	"github.com/gobuffalo/validate"
+	"github.com/u007/gobinder/lib"
 )
 
 type BinderBaseModel interface {
 	BinderInit(*DGraphTxn, context.Context, *ModelBinder) error
 }
 
+// register field change callbacks via binder.OnChange for every binder of this model.
+// the binder points at the one being built and NewModelBinder returns a copy of it,
+// so callbacks must not keep the pointer past the hook, use the binder given to later hooks
+type BaseChangeObservable interface {
+	BinderOnChange(*DGraphTxn, context.Context, *ModelBinder) error
+}
+
 type BasePreValidatable interface {
 	PreValidate(*DGraphTxn, context.Context, string, *ModelBinder) error
 }
@@ -42,7 +51,9 @@ type BaseAfterDestroy interface {
 // @param model -> class only, exclude value
 // @param map[string]interface for values
 //only accept 1 values, as optional
+// returns a copy, a binder pointer kept by BinderInit or BinderOnChange does not refer to it
 func NewModelBinder(tx *DGraphTxn, ctx context.Context, model interface{}, values ...map[string]interface{}) ModelBinder {
+	ctx = tx.withConfig(ctx)
 	binder := ModelBinder{model: model, Context: ctx}
 	binder.Changes = map[string][]interface{}{}
 
@@ -53,7 +64,18 @@ func NewModelBinder(tx *DGraphTxn, ctx context.Context, model interface{}, value
 		binder.ModelNew = &yesNew
 
 		if binderModel, ok := model.(BinderBaseModel); ok {
-			binderModel.BinderInit(tx, ctx, &binder)
+			traceHook(tx, ctx, "BinderInit", func(ctx context.Context) error {
+				return binderModel.BinderInit(tx, ctx, &binder)
+			})
+		}
+	}
+
+	if observable, ok := model.(BaseChangeObservable); ok {
+		if err := traceHook(tx, ctx, "BinderOnChange", func(ctx context.Context) error {
+			return observable.BinderOnChange(tx, ctx, &binder)
+		}); err != nil {
+			logging(ctx).Errorf("BinderOnChange error: %+v", err)
+			binder.SetErr(err)
 		}
 	}
 
@@ -68,6 +90,35 @@ func NewModelBinderAddr(tx *DGraphTxn, ctx context.Context, model interface{}, v
 	return &binder
 }
 
+// binder safe to share between concurrent graphql resolvers
+func NewConcurrentModelBinder(tx *DGraphTxn, ctx context.Context, model interface{}, values ...map[string]interface{}) *ModelBinder {
+	binder := NewModelBinder(tx, ctx, model)
+	binder.Concurrent()
+	if len(values) > 0 {
+		binder.Sets(values[0], true)
+	}
+	return &binder
+}
+
+// restore binder from json of a previous binder.MarshalJSON, BinderInit is not called again
+func ResumeModelBinder(tx *DGraphTxn, ctx context.Context, model interface{}, state []byte) (*ModelBinder, error) {
+	ctx = tx.withConfig(ctx)
+	binder := ModelBinder{model: model, Context: ctx}
+	binder.Changes = map[string][]interface{}{}
+	if err := binder.UnmarshalJSON(state); err != nil {
+		return nil, err
+	}
+
+	if observable, ok := model.(BaseChangeObservable); ok {
+		if err := traceHook(tx, ctx, "BinderOnChange", func(ctx context.Context) error {
+			return observable.BinderOnChange(tx, ctx, &binder)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return &binder, nil
+}
+
 type SaveCallBack func(*ModelBinder) error
 
 type SaveHelper struct {
@@ -119,18 +170,18 @@ func (s *SaveHelper) Exec() (bool, *validate.Errors, error) {
 		}
 	}
 
-	if err := query.Clone().Where(where, whereValues).First(ctx, newModel); err == nil {
-		// val := reflect.ValueOf(dataModel)
-		// logging(ctx).Debugf("found existing: %#v", val)
-		binder := NewModelBinder(tx, ctx, newModel, values)
-		reflect.ValueOf(dataModel).Elem().Set(reflect.ValueOf(newModel).Elem())
+	load := func(existing interface{}) error {
+		binder := NewModelBinder(tx, ctx, existing, values)
+		reflect.ValueOf(dataModel).Elem().Set(reflect.ValueOf(existing).Elem())
 		if s.onLoad != nil {
-			if err := (*s.onLoad)(&binder); err != nil {
-				return false, nil, err
-			}
+			return (*s.onLoad)(&binder)
 		}
+		return nil
+	}
+
+	if err := query.Clone().Where(where, whereValues).First(ctx, newModel); err == nil {
 		// logging(ctx).Debugf("found existing: %#v", dataModel)
-		return false, nil, nil
+		return false, nil, load(newModel)
 	} else if err != ErrNotFound {
 		logging(ctx).Errorf("error: %+v\n", err)
 		return false, nil, err
@@ -143,13 +194,21 @@ func (s *SaveHelper) Exec() (bool, *validate.Errors, error) {
 		}
 	}
 
-	verrs, err := SaveBinder(tx, ctx, &binder)
+	//guards against a concurrent create between the lookup above and this save
+	created, verrs, err := saveBinderIfAbsent(tx, ctx, &binder, where, whereValues)
 	if err != nil {
 		return false, verrs, err
 	}
+	if !created {
+		existing := reflect.New(reflect.ValueOf(dataModel).Elem().Type()).Interface()
+		if err := query.Clone().Where(where, whereValues).First(ctx, existing); err != nil {
+			return false, verrs, err
+		}
+		return false, nil, load(existing)
+	}
 
 	id := reflect.ValueOf(newModel).Elem().FieldByName("UID").Interface().(string)
-	logging(ctx).Debugf("looking up id %s for model %#v", id, newModel)
+	structuredLogging(ctx).Debugw("looking up id", "uid", id, "model", Redacted(newModel))
 	if err := query.Find(ctx, newModel, id); err != nil {
 		return false, verrs, err
 	}
@@ -173,6 +232,8 @@ func (s *SaveHelper) Exec() (bool, *validate.Errors, error) {
 	@param variables for the where condition
 	@param queryFields - to obtain if exists
 	@return model, created t/f, validation errors, error
+	create is a single upsert request, concurrent calls create at most 1 node
+	only when the predicates of where are indexed with @upsert (dgraph:"upsert" tag)
 */
 func SaveIfNotExist(tx *DGraphTxn, ctx context.Context, dataModel interface{}, values map[string]interface{},
 	where string, whereValues map[string]QVar, queryFields ...QueryField) *SaveHelper {
@@ -234,93 +295,307 @@ func Save(tx *DGraphTxn, ctx context.Context, dataModel interface{}, values map[
 	return &sHelper
 }
 
-func SaveBinder(tx *DGraphTxn, ctx context.Context, binder *ModelBinder) (*validate.Errors, error) {
+func SaveBinder(tx *DGraphTxn, ctx context.Context, binder *ModelBinder) (verrs *validate.Errors, err error) {
+	if err := tx.checkWritable("save binder"); err != nil {
+		return validate.NewErrors(), err
+	}
+	model := binder.Model()
+	action := "update"
+	if binder.Get("UID") == "" {
+		action = "create"
+	}
+	ctx, span := tx.config(ctx).StartSpan(ctx, "binder.SaveBinder",
+		lib.Attr("model", reflect.TypeOf(model).String()), lib.Attr("action", action))
+	defer func() {
+		span.SetAttributes(lib.Attr("uid", binder.Get("UID")))
+		lib.EndSpan(span, err)
+	}()
+
+	return saveBinder(tx, ctx, binder)
+}
+
+// delete node of model by its UID, running BeforeDestroy / AfterDestroy and writing a destroy audit
+func DestroyModel(tx *DGraphTxn, ctx context.Context, model interface{}) (err error) {
+	if err := tx.checkWritable("destroy model"); err != nil {
+		return err
+	}
+	uid := reflect.Indirect(reflect.ValueOf(model)).FieldByName("UID").Interface().(string)
+	ctx, span := tx.config(ctx).StartSpan(ctx, "binder.DestroyModel",
+		lib.Attr("model", reflect.TypeOf(model).String()), lib.Attr("uid", uid))
+	defer func() {
+		lib.EndSpan(span, err)
+	}()
+
+	if destroyable, ok := model.(BaseBeforeDestroy); ok {
+		if err := traceHook(tx, ctx, "BeforeDestroy", func(ctx context.Context) error {
+			return destroyable.BeforeDestroy(tx, ctx)
+		}); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.DeleteByUID(ctx, uid, false); err != nil {
+		return err
+	}
+	if err := WriteAudit(tx, ctx, "destroy", model, nil); err != nil {
+		return err
+	}
+	if destroyable, ok := model.(BaseAfterDestroy); ok {
+		if err := traceHook(tx, ctx, "AfterDestroy", func(ctx context.Context) error {
+			return destroyable.AfterDestroy(tx, ctx)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveBinder(tx *DGraphTxn, ctx context.Context, binder *ModelBinder) (*validate.Errors, error) {
+	vEmptyErrors := validate.NewErrors()
+	plan, verrs, err := prepareSave(tx, ctx, binder)
+	if err != nil {
+		return verrs, err
+	}
+
+	mu, err := plan.mutation("new")
+	if err != nil {
+		plan.rollback(binder)
+		return vEmptyErrors, err
+	}
+	uid := plan.id
+	if plan.versioned && plan.action == "update" {
+		if err := saveVersioned(tx, ctx, plan.id, mu, plan.versionField.Tag.Get("json"), plan.version); err != nil {
+			plan.rollback(binder)
+			return vEmptyErrors, err
+		}
+	} else {
+		resp, err := tx.Mutate(ctx, mu)
+		if err != nil {
+			plan.rollback(binder)
+			return vEmptyErrors, err
+		}
+		if plan.action == "create" {
+			uid = resp.Uids["new"]
+			// gcontext.Logger.Debugf("new uid: %+v", model)
+		}
+	}
+
+	if err := applySave(binder, plan, uid); err != nil {
+		return vEmptyErrors, err
+	}
+	if err := completeSave(tx, ctx, binder, plan); err != nil {
+		return vEmptyErrors, err
+	}
+	return vEmptyErrors, nil
+}
+
+// hash and version of a validated binder, ready to be written
+type savePlan struct {
+	action       string
+	id           string
+	hash         map[string]interface{}
+	versionField reflect.StructField
+	versioned    bool
+	version      int64             //loaded version
+	passwords    map[string]string //predicate to plaintext of `dgraph:"password"` fields
+	stamped      []stampedField    //CreatedAt / UpdatedAt set by prepareSave
+}
+
+// timestamp field set by prepareSave, with its value and change before
+type stampedField struct {
+	name   string
+	value  interface{}
+	change []interface{} //nil when no change was recorded
+}
+
+// set timestamp on binder before the hooks run, as a change
+// fields missing on the model are skipped
+func stampField(binder *ModelBinder, name string, at time.Time) (stampedField, bool) {
+	if _, ok := reflect.TypeOf(binder.Model()).Elem().FieldByName(name); !ok {
+		return stampedField{}, false
+	}
+	before := stampedField{name: name, value: binder.Get(name), change: binder.ChangesSnapshot()[name]}
+	if err := binder.Set(name, at, true); err != nil {
+		return stampedField{}, false
+	}
+	return before, true
+}
+
+// undo timestamps set by prepareSave, when the plan is not written
+func (plan *savePlan) rollback(binder *ModelBinder) {
+	rollbackStamps(binder, plan.stamped)
+}
+
+func rollbackStamps(binder *ModelBinder, stamped []stampedField) {
+	for i := len(stamped) - 1; i >= 0; i-- {
+		binder.Restore(stamped[i].name, stamped[i].value, stamped[i].change)
+	}
+}
+
+// hash and passwords of plan as 1 mutation, a new model is written as blank node _:<blank>
+func (plan *savePlan) mutation(blank string) (*api.Mutation, error) {
+	subject := plan.id
+	if plan.action == "create" {
+		subject = "_:" + blank
+		plan.hash["uid"] = subject
+	}
+	dataJson, err := json.Marshal(plan.hash)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := passwordNQuads(subject, plan.passwords)
+	if err != nil {
+		return nil, err
+	}
+	return &api.Mutation{SetJson: dataJson, Set: sets}, nil
+}
+
+// set timestamps, run PreValidate and BinderValidate, build hash with next version
+// timestamps are rolled back when an error is returned, callers roll back the plan when its write fails
+func prepareSave(tx *DGraphTxn, ctx context.Context, binder *ModelBinder) (plan *savePlan, verrs *validate.Errors, err error) {
 	vEmptyErrors := validate.NewErrors()
+	if err := binder.Err(); err != nil {
+		return nil, vEmptyErrors, err
+	}
+	config := tx.config(ctx)
 	var action string
 	model := binder.Model()
 	value := reflect.ValueOf(model).Elem()
 	// field := value.FieldByName("ID")
 	id := value.FieldByName("UID").Interface().(string)
+	stamped := []stampedField{}
+	stamp := func(name string, at time.Time) {
+		if before, ok := stampField(binder, name, at); ok {
+			stamped = append(stamped, before)
+		}
+	}
+	defer func() {
+		if err != nil {
+			rollbackStamps(binder, stamped)
+		}
+	}()
 	if id == "" {
 		action = "create"
-		if SetCreatedUpdatedTimeOnSave {
+		if config.SetCreatedUpdatedTimeOnSave {
 			thetime := time.Now().UTC()
-			binder.Set("UpdatedAt", thetime, true)
-			binder.Set("CreatedAt", thetime, true)
+			stamp("UpdatedAt", thetime)
+			stamp("CreatedAt", thetime)
 		}
 
 	} else {
 		action = "update"
-		if SetCreatedUpdatedTimeOnSave {
-			binder.Set("UpdatedAt", time.Now().UTC(), true)
+		if config.SetCreatedUpdatedTimeOnSave {
+			stamp("UpdatedAt", time.Now().UTC())
 		}
 	}
 
 	hook1, ok1 := model.(BasePreValidatable)
 	if ok1 {
-		if err := hook1.PreValidate(tx, ctx, action, binder); err != nil {
-			return vEmptyErrors, err //return original error
+		if err := traceHook(tx, ctx, "PreValidate", func(ctx context.Context) error {
+			return hook1.PreValidate(tx, ctx, action, binder)
+		}); err != nil {
+			return nil, vEmptyErrors, err //return original error
 		}
 	}
 
 	hookBinder, ok1 := model.(BaseBinderValidatable)
 	if ok1 {
-		verrs, err := hookBinder.BinderValidate(tx, ctx, action, binder)
+		err := traceHook(tx, ctx, "BinderValidate", func(ctx context.Context) error {
+			var err error
+			verrs, err = hookBinder.BinderValidate(tx, ctx, action, binder)
+			return err
+		})
 
 		if err != nil {
 			// gcontext.Logger.Errorf(fmt.Sprintf("BinderValidate error: %+v", err))
-			return verrs, err //return original error
+			return nil, verrs, err //return original error
 		}
 		if verrs.HasAny() {
-			return verrs, fmt.Errorf("validation error: %#v", verrs.Errors) //return new error
+			config.MetricsRecorder().IncValidationFailure(model.(TableNameAble).TableName())
+			return nil, verrs, fmt.Errorf("validation error: %#v", verrs.Errors) //return new error
 		}
 	}
 
-	structT := structs.New(model)
-	structT.TagName = "json"
 	hash := map[string]interface{}{}
+	passwords := map[string]string{}
 	if err := ForEachField(model, func(i int, field *reflect.Value, stField reflect.StructField) error {
 		// logging(ctx).Debugf("field: %#v", stField)
+		if isPasswordField(stField) {
+			if plaintext := reflect.Indirect(*field); plaintext.IsValid() && plaintext.String() != "" {
+				passwords[stField.Tag.Get("json")] = plaintext.String()
+			}
+			return nil
+		}
+		if geo, ok := geoFieldValue(*field); ok {
+			if !geo.IsZero() {
+				hash[stField.Tag.Get("json")] = geo
+			}
+			return nil
+		}
+		if isFacetField(stField) {
+			return nil
+		}
 		if IsStructOrIsSlicesOfStruct(field.Interface()) {
+			//relations are not written, except edges of changed relations declaring facets
+			if edges, ok := edgeFacetRows(*field, stField.Tag.Get("json")); ok && binder.Changed(stField.Name) && len(edges) > 0 {
+				hash[stField.Tag.Get("json")] = edges
+			}
 			return nil
 		}
-		jsonName := stField.Tag.Get("json")
-		hash[jsonName] = field.Interface()
+		for predicate, value := range langHashEntries(stField, field.Interface()) {
+			hash[predicate] = value
+		}
 
 		return nil
 	}); err != nil {
-		return vEmptyErrors, err
+		return nil, vEmptyErrors, err
 	}
 	if len(hash) == 0 {
-		return vEmptyErrors, fmt.Errorf("Nothing to save")
+		return nil, vEmptyErrors, fmt.Errorf("Nothing to save")
 	}
-	// hash := structT.Map()
 	hash["_type"] = model.(TableNameAble).TableName()
-	if DEBUG_MUTATION {
-		logging(ctx).Debugf("Saving-hash: %#v", hash)
-	}
-	// dataJson, err := json.Marshal(hash)
-	// if err != nil {
-	// 	return vEmptyErrors, err
-	// }
-	assigned, err := tx.Save(ctx, hash, false)
-	if err != nil {
-		return vEmptyErrors, err
+	if config.DebugMutation {
+		structuredLogging(ctx).Debugw("saving hash", "model", hash["_type"], "uid", id, "action", action, "hash", RedactHash(model, hash))
 	}
 
-	if binder.Get("UID") == "" {
-		refValue := reflect.Indirect(reflect.ValueOf(model))
-		refValue.FieldByName("UID").SetString(assigned.Uids["blank-0"])
-		// gcontext.Logger.Debugf("new uid: %+v", model)
+	plan = &savePlan{action: action, id: id, hash: hash, passwords: passwords, stamped: stamped}
+	plan.versionField, plan.versioned = versionField(model)
+	if plan.versioned {
+		plan.version = loadedVersion(binder, plan.versionField)
+		hash[plan.versionField.Tag.Get("json")] = plan.version + 1
 	}
+	return plan, vEmptyErrors, nil
+}
 
-	hook2, ok2 := model.(BaseAfterSavable)
+// after plan is written: set uid of a new model and version, clear passwords
+func applySave(binder *ModelBinder, plan *savePlan, uid string) error {
+	model := binder.Model()
+	if plan.action == "create" {
+		reflect.Indirect(reflect.ValueOf(model)).FieldByName("UID").SetString(uid)
+	}
+	if plan.versioned {
+		setVersion(model, plan.versionField, plan.version+1)
+	}
+	clearPasswords(binder)
+	return nil
+}
+
+// after applySave: write audit, run AfterSave
+func completeSave(tx *DGraphTxn, ctx context.Context, binder *ModelBinder, plan *savePlan) error {
+	if err := WriteAudit(tx, ctx, plan.action, binder.Model(), binder); err != nil {
+		return err
+	}
+	return afterSave(tx, ctx, binder, plan)
+}
+
+func afterSave(tx *DGraphTxn, ctx context.Context, binder *ModelBinder, plan *savePlan) error {
+	hook2, ok2 := binder.Model().(BaseAfterSavable)
 	if ok2 {
-		if err := hook2.AfterSave(tx, ctx, action, binder); err != nil {
+		if err := traceHook(tx, ctx, "AfterSave", func(ctx context.Context) error {
+			return hook2.AfterSave(tx, ctx, plan.action, binder)
+		}); err != nil {
 			// gcontext.Logger.Errorf(fmt.Sprintf("postsave error: %+v", err))
-			return vEmptyErrors, err //return original error
+			return err //return original error
 		}
 	}
-
-	return vEmptyErrors, nil
+	return nil
 }
@@ -1,10 +1,18 @@
 package dgraph_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/dgraph-io/dgo/v2/protos/api"
 	_ "github.com/u007/gobinder"
+	"github.com/u007/gobinder/lib"
 
 	graphql "github.com/graph-gophers/graphql-go"
 )
@@ -50,7 +58,7 @@ type TestModel struct {
 	Last_name          *string    `json:"last_name"`
 	Mobile_no          *string    `json:"mobile_no"`
 	Email              *string    `json:"email"`
-	RegistrationCode   string     `json:"registration_code"`
+	RegistrationCode   string     `json:"registration_code" binder:"sensitive"`
 	ResetExpiredAt     *time.Time `json:"reset_expired_at"`
 	VerificationStatus bool       `json:"verification_status"`
 	SignupType         string     `json:"signup_type"`
@@ -195,3 +203,1111 @@ func (as *ModelTestSuite) TestBaseBinder() {
 	binder.SetValue("VerificationStatus", reflect.ValueOf("0"), true)
 	as.True(test2.VerificationStatus == false)
 }
+
+func (a *TestSuite) TestBinderOnChange() {
+	tx := a.Tx
+	var user TestModel
+	binder := NewModelBinder(tx, a.Context, &user)
+	user.VerificationStatus = true
+
+	binder.OnChange("Email", func(old interface{}, new interface{}) error {
+		return binder.Set("VerificationStatus", false, true)
+	})
+	testEmail := "test@example.com"
+	a.NoError(binder.Set("Email", testEmail, true))
+	a.True(*user.Email == testEmail)
+	a.False(user.VerificationStatus)
+	a.True(binder.Changed("VerificationStatus"))
+
+	binder.OnChange("Name", func(old interface{}, new interface{}) error {
+		return fmt.Errorf("name is locked")
+	})
+	err := binder.Set("Name", "bbb", true)
+	a.True(err != nil)
+	a.True(user.Name == "")
+	a.False(binder.Changed("Name"))
+
+	//sets made by an aborting callback are rolled back with it
+	binder.OnChange("First_name", func(old interface{}, new interface{}) error {
+		if err := binder.Set("VerificationStatus", true, true); err != nil {
+			return err
+		}
+		return fmt.Errorf("phone is locked")
+	})
+	a.Error(binder.Set("First_name", "ccc", true))
+	a.False(user.VerificationStatus)
+	a.True(binder.Changed("VerificationStatus"))
+	a.Equal(false, binder.Changes["VerificationStatus"][1])
+	a.True(user.First_name == "")
+	a.False(binder.Changed("First_name"))
+}
+
+type TestLockedDocument struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+}
+
+func (t TestLockedDocument) TableName() string {
+	return "test_documents"
+}
+
+func (t *TestLockedDocument) BinderOnChange(tx *DGraphTxn, ctx context.Context, binder *ModelBinder) error {
+	return fmt.Errorf("document is locked")
+}
+
+func (a *TestSuite) TestBinderOnChangeError() {
+	tx := a.Tx
+	var doc TestLockedDocument
+	binder := NewModelBinder(tx, a.Context, &doc)
+	a.True(binder.Err() != nil)
+	binder.Set("Title", "draft", true)
+	_, err := SaveBinder(tx, a.Context, &binder)
+	a.True(err != nil)
+	a.True(doc.UID == "")
+
+	state, err := json.Marshal(&binder)
+	a.NoError(err)
+	_, err = ResumeModelBinder(tx, a.Context, &doc, state)
+	a.Equal("document is locked", err.Error())
+}
+
+func (a *TestSuite) TestBinderRelationChanges() {
+	tx := a.Tx
+	user := TestModel{UID: "0x1", TestRoles: &[]TestRole{
+		{UID: "0x10", Name: "admin"},
+		{UID: "0x11", Name: "staff"},
+	}}
+	binder := NewModelBinder(tx, a.Context, &user)
+	a.False(binder.Changed("TestRoles"))
+
+	err := binder.Set("TestRoles", []interface{}{
+		map[string]interface{}{"uid": "0x10", "name": "administrator"},
+		map[string]interface{}{"uid": "0x12", "name": "guest"},
+		map[string]interface{}{"name": "new role"},
+	}, true)
+	a.NoError(err)
+
+	changes, err := binder.RelationChanges("TestRoles")
+	a.NoError(err)
+	a.True(len(changes.Added) == 2, "added: %#v", changes.Added)
+	a.Equal([]string{"0x12"}, changes.AddedUIDs())
+	a.Equal([]string{"0x11"}, changes.RemovedUIDs())
+	a.Equal([]string{"0x10"}, changes.ModifiedUIDs())
+
+	_, err = binder.RelationChanges("Name")
+	a.True(err != nil)
+	_, err = binder.RelationChanges("Start_at")
+	a.True(err != nil)
+
+	//clear is recorded, every element is removed, time fields are not relations
+	user.Start_at = time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	a.NoError(binder.ClearRelation())
+	a.True(user.TestRoles == nil)
+	a.Equal(2020, user.Start_at.Year())
+	changes, err = binder.RelationChanges("TestRoles")
+	a.NoError(err)
+	a.ElementsMatch([]string{"0x10", "0x12"}, changes.RemovedUIDs())
+	a.Len(changes.Removed, 3) //including the element without uid
+
+	//reset zeroes relations and drops their changes
+	binder.Set("TestRoles", []interface{}{map[string]interface{}{"uid": "0x13", "name": "owner"}}, true)
+	a.True(binder.Changed("TestRoles"))
+	a.NoError(binder.ResetRelation())
+	a.True(user.TestRoles == nil)
+	a.False(binder.Changed("TestRoles"))
+}
+
+type TestLooseModel struct {
+	UID   string      `json:"uid"`
+	Extra interface{} `json:"extra"`
+	Roles []TestRole  `json:"roles"`
+}
+
+func (a *TestSuite) TestBinderRelationChangesWithoutUID() {
+	model := TestLooseModel{Roles: []TestRole{{Name: "a"}, {Name: "b"}}}
+	binder := NewModelBinder(a.Tx, a.Context, &model)
+
+	_, err := binder.RelationChanges("Extra")
+	a.True(err != nil)
+
+	a.NoError(binder.Set("Roles", []interface{}{
+		map[string]interface{}{"name": "a"},
+		map[string]interface{}{"name": "c"},
+	}, true))
+	changes, err := binder.RelationChanges("Roles")
+	a.NoError(err)
+	a.Equal([]interface{}{TestRole{Name: "c"}}, changes.Added)
+	a.Equal([]interface{}{TestRole{Name: "b"}}, changes.Removed)
+	a.Empty(changes.RemovedUIDs())
+}
+
+func (a *TestSuite) TestAuditTrail() {
+	AuditTrail = true
+	AuditExcludedFields = []string{"registration_code"}
+	defer func() {
+		AuditTrail = false
+		AuditExcludedFields = []string{}
+	}()
+
+	tx := a.Tx
+	ctx := WithAuditActor(a.Context, "tester")
+	var user TestModel
+	binder := NewModelBinder(tx, ctx, &user)
+	binder.Set("Name", "aaa", true)
+	binder.Set("RegistrationCode", "secret", true)
+	_, err := SaveBinder(tx, ctx, &binder)
+	a.NoError(err)
+
+	binder = NewModelBinder(tx, ctx, &user)
+	binder.Set("Name", "bbb", true)
+	_, err = SaveBinder(tx, ctx, &binder)
+	a.NoError(err)
+
+	history, err := AuditHistory(tx, ctx, user.UID)
+	a.NoError(err)
+	a.True(len(history) == 2, "history: %#v", history)
+	a.Equal("create", history[0].Action)
+	a.Equal("update", history[1].Action)
+	a.Equal("tester", history[1].Actor)
+	a.Equal("test_models", history[1].Model)
+
+	diff, err := history[0].Diff()
+	a.NoError(err)
+	_, hasCode := diff["registration_code"]
+	a.False(hasCode)
+	diff, err = history[1].Diff()
+	a.NoError(err)
+	a.Equal("aaa", diff["name"].Old)
+	a.Equal("bbb", diff["name"].New)
+
+	uid := user.UID
+	a.NoError(DestroyModel(tx, ctx, &user))
+	history, err = AuditHistory(tx, ctx, uid)
+	a.NoError(err)
+	a.True(len(history) == 3, "history: %#v", history)
+	a.Equal("destroy", history[2].Action)
+	a.Equal("tester", history[2].Actor)
+
+	var note TestNote
+	binder = NewModelBinder(tx, ctx, &note)
+	binder.Set("Body", "hello", true)
+	_, err = SaveBinder(tx, ctx, &binder)
+	a.NoError(err)
+	history, err = AuditHistory(tx, ctx, note.UID)
+	a.NoError(err)
+	a.True(len(history) == 1, "history: %#v", history)
+	diff, err = history[0].Diff()
+	a.NoError(err)
+	a.Equal("hello", diff["body"].New)
+	_, hasRaw := diff["body,omitempty"]
+	a.False(hasRaw)
+}
+
+type TestNote struct {
+	UID  string `json:"uid,omitempty"`
+	Body string `json:"body,omitempty"`
+}
+
+func (t TestNote) TableName() string {
+	return "test_notes"
+}
+
+type TestDocument struct {
+	UID     string `json:"uid"`
+	Title   string `json:"title"`
+	Version int    `json:"version" binder:"version"`
+}
+
+func (t TestDocument) TableName() string {
+	return "test_documents"
+}
+
+func (a *TestSuite) TestBinderVersionLocking() {
+	config := DefaultConfig()
+	config.SetCreatedUpdatedTimeOnSave = false
+	ctx := WithConfig(a.Context, config)
+
+	tx := a.Tx
+	var doc TestDocument
+	binder := NewModelBinder(tx, ctx, &doc)
+	binder.Set("Title", "draft", true)
+	_, err := SaveBinder(tx, ctx, &binder)
+	a.NoError(err)
+	a.Equal(1, doc.Version)
+
+	tx = a.MustCommitTx()
+	editorA := doc
+	editorB := doc
+
+	binderA := NewModelBinder(tx, ctx, &editorA)
+	binderA.Set("Title", "by a", true)
+	_, err = SaveBinder(tx, ctx, &binderA)
+	a.NoError(err)
+	a.Equal(2, editorA.Version)
+
+	binderB := NewModelBinder(tx, ctx, &editorB)
+	binderB.Set("Title", "by b", true)
+	_, err = SaveBinder(tx, ctx, &binderB)
+	a.True(errors.Is(err, ErrStaleObject), "expected stale: %+v", err)
+	a.Equal(1, editorB.Version)
+}
+
+type TestStampedNote struct {
+	UID       string    `json:"uid,omitempty"`
+	Body      string    `json:"body,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (t TestStampedNote) TableName() string {
+	return "test_stamped_notes"
+}
+
+// UpdatedAt as seen by the last PreValidate
+var stampedNoteSeen struct {
+	updatedAt time.Time
+	changed   bool
+}
+
+func (t *TestStampedNote) PreValidate(tx *DGraphTxn, ctx context.Context, action string, binder *ModelBinder) error {
+	stampedNoteSeen.updatedAt = t.UpdatedAt
+	stampedNoteSeen.changed = binder.Changed("UpdatedAt")
+	if t.Body == "rejected" {
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func (a *TestSuite) TestSaveBinderTimestamps() {
+	var note TestStampedNote
+	binder := NewModelBinder(a.Tx, a.Context, &note)
+	binder.Set("Body", "stamped", true)
+	_, err := SaveBinder(a.Tx, a.Context, &binder)
+	a.NoError(err)
+	a.False(stampedNoteSeen.updatedAt.IsZero(), "timestamps are set before hooks")
+	a.True(stampedNoteSeen.changed)
+	a.Equal(note.UpdatedAt, stampedNoteSeen.updatedAt)
+	a.Equal(note.UpdatedAt, note.CreatedAt)
+
+	//failed save restores timestamps and their changes
+	saved := note.UpdatedAt
+	binder = NewModelBinder(a.Tx, a.Context, &note)
+	binder.Set("Body", "rejected", true)
+	_, err = SaveBinder(a.Tx, a.Context, &binder)
+	a.EqualError(err, "rejected")
+	a.False(stampedNoteSeen.updatedAt.Before(saved))
+	a.Equal(saved, note.UpdatedAt)
+	a.False(binder.Changed("UpdatedAt"))
+	a.True(binder.Changed("Body"))
+}
+
+// records names of started spans
+type spanRecorder struct {
+	lock  sync.Mutex
+	names []string
+}
+
+type recordedSpan struct{}
+
+func (s recordedSpan) SetAttributes(attrs ...lib.Attribute) {}
+func (s recordedSpan) RecordError(err error)                {}
+func (s recordedSpan) End()                                 {}
+
+func (r *spanRecorder) Start(ctx context.Context, name string, attrs ...lib.Attribute) (context.Context, lib.Span) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.names = append(r.names, name)
+	return ctx, recordedSpan{}
+}
+
+type TestHookedNote struct {
+	UID  string `json:"uid,omitempty"`
+	Body string `json:"body,omitempty"`
+}
+
+func (t TestHookedNote) TableName() string {
+	return "test_hooked_notes"
+}
+
+func (t *TestHookedNote) BinderInit(tx *DGraphTxn, ctx context.Context, binder *ModelBinder) error {
+	return binder.Set("Body", "init", true)
+}
+
+func (t *TestHookedNote) BeforeDestroy(tx *DGraphTxn, ctx context.Context) error {
+	return nil
+}
+
+func (t *TestHookedNote) AfterDestroy(tx *DGraphTxn, ctx context.Context) error {
+	return nil
+}
+
+func (a *TestSuite) TestHookSpans() {
+	recorder := &spanRecorder{}
+	config := DefaultConfig()
+	config.Tracer = recorder
+	ctx := WithConfig(a.Context, config)
+
+	var note TestHookedNote
+	binder := NewModelBinder(a.Tx, ctx, &note)
+	_, err := SaveBinder(a.Tx, ctx, &binder)
+	a.NoError(err)
+	a.NoError(DestroyModel(a.Tx, ctx, &note))
+	for _, name := range []string{"binder.BinderInit", "binder.BeforeDestroy", "binder.AfterDestroy"} {
+		a.Contains(recorder.names, name)
+	}
+}
+
+// run with go test -race
+func (a *TestSuite) TestConcurrentBinder() {
+	tx := a.Tx
+	var user TestModel
+	binder := NewConcurrentModelBinder(tx, a.Context, &user)
+	a.True(binder.IsConcurrent())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			errs <- binder.Set("Status", i, true)
+			errs <- binder.Set("RegistrationCode", fmt.Sprintf("code-%d", i), true)
+		}(i)
+		go func() {
+			defer wg.Done()
+			binder.Changed("Status")
+			binder.OldValue("RegistrationCode")
+			binder.IsNew()
+		}()
+		go func() {
+			defer wg.Done()
+			binder.Dirty()
+			for range binder.ChangesSnapshot() {
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		a.NoError(err)
+	}
+
+	a.True(binder.Dirty())
+	a.True(binder.Changed("RegistrationCode"))
+	//recorded change matches the final value
+	a.Equal(user.Status, binder.ChangesSnapshot()["Status"][1])
+	a.Equal(user.RegistrationCode, binder.ChangesSnapshot()["RegistrationCode"][1])
+}
+
+func (a *TestSuite) TestBinderResume() {
+	tx := a.Tx
+	var user TestModel
+	binder := NewModelBinder(tx, a.Context, &user)
+	binder.Set("Name", "draft", true)
+	binder.Set("Email", "test@example.com", true)
+	timeNow := time.Now().UTC()
+	binder.Set("ResetExpiredAt", timeNow, true)
+
+	state, err := json.Marshal(&binder)
+	a.NoError(err)
+
+	var resumed TestModel
+	binder2, err := ResumeModelBinder(tx, a.Context, &resumed, state)
+	a.NoError(err)
+	a.True(binder2.IsNew())
+	a.True(binder2.Dirty())
+	a.True(binder2.Changed("Name"))
+	a.True(binder2.Changed("Email"))
+	a.False(binder2.Changed("Status"))
+	a.Equal("", binder2.OldValue("Name"))
+	a.True(binder2.OldValue("Email").(*string) == nil)
+	a.Equal("test@example.com", *resumed.Email)
+	a.True(resumed.ResetExpiredAt.Equal(timeNow))
+
+	_, err = SaveBinder(tx, a.Context, binder2)
+	a.NoError(err)
+	a.True(resumed.UID != "")
+
+	var other TestRole
+	_, err = ResumeModelBinder(tx, a.Context, &other, state)
+	a.True(err != nil)
+
+	//secrets are left out of drafts, binder error is kept
+	var account TestAccount
+	accountBinder := NewModelBinder(tx, a.Context, &account)
+	accountBinder.Set("Name", "draft-account", true)
+	accountBinder.Set("Password", "draft-pass", true)
+	accountBinder.SetErr(errors.New("callback failed"))
+	state, err = json.Marshal(&accountBinder)
+	a.NoError(err)
+	a.NotContains(string(state), "draft-pass")
+	a.Equal("draft-pass", account.Password)
+
+	var resumedAccount TestAccount
+	binder3, err := ResumeModelBinder(tx, a.Context, &resumedAccount, state)
+	a.NoError(err)
+	a.Equal("draft-account", resumedAccount.Name)
+	a.Equal("", resumedAccount.Password)
+	a.False(binder3.Changed("Password"))
+	a.EqualError(binder3.Err(), "callback failed")
+}
+
+func (a *TestSuite) TestBinderFieldMask() {
+	tx := a.Tx
+	user := TestModel{UID: "0x1", Name: "aaa", Status: 1, SingleRole: &TestRole{UID: "0x10", Name: "admin"}}
+	binder := NewModelBinder(tx, a.Context, &user)
+
+	payload := map[string]interface{}{
+		"name":        "bbb",
+		"status":      float64(2),
+		"single_role": map[string]interface{}{"name": "staff"},
+	}
+	err := binder.ApplyFieldMask(payload, []string{"name", "single_role.name"}, true)
+	a.NoError(err)
+	a.Equal("bbb", user.Name)
+	a.Equal(1, user.Status) //not in mask
+	a.Equal("staff", user.SingleRole.Name)
+	a.Equal("0x10", user.SingleRole.UID)
+	a.Equal([]string{"name", "single_role.name"}, binder.FieldMask())
+
+	err = binder.ApplyFieldMask(payload, []string{"status", "unknown"}, true)
+	a.True(err != nil)
+	a.Equal(1, user.Status) //rejected as a whole
+
+	err = binder.ApplyFieldMask(payload, []string{"test_roles.name"}, true)
+	a.True(err != nil)
+
+	//tag options are not part of the path
+	note := TestNote{UID: "0x2", Body: "draft"}
+	noteBinder := NewModelBinder(tx, a.Context, &note)
+	a.NoError(noteBinder.ApplyFieldMask(map[string]interface{}{"body": "final"}, []string{"body"}, true))
+	a.Equal("final", note.Body)
+	a.Equal([]string{"body"}, noteBinder.FieldMask())
+}
+
+type TestLead struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+}
+
+type TestTeam struct {
+	UID  string    `json:"uid"`
+	Name string    `json:"name"`
+	Lead *TestLead `json:"lead"`
+}
+
+type TestOrg struct {
+	UID  string    `json:"uid"`
+	Team *TestTeam `json:"team"`
+}
+
+func (a *TestSuite) TestBinderFieldMaskThroughPointers() {
+	lead := &TestLead{UID: "0x21", Name: "ann"}
+	team := &TestTeam{UID: "0x20", Name: "core", Lead: lead}
+	org := TestOrg{UID: "0x1", Team: team}
+	binder := NewModelBinder(a.Tx, a.Context, &org)
+
+	payload := map[string]interface{}{
+		"team": map[string]interface{}{"lead": map[string]interface{}{"name": "bob"}},
+	}
+	a.NoError(binder.ApplyFieldMask(payload, []string{"team.lead.name"}, true))
+	a.Equal("bob", org.Team.Lead.Name)
+	a.Equal("0x21", org.Team.Lead.UID)
+	a.Equal("core", org.Team.Name)
+	//previous nested structs are untouched, so the recorded old value stays intact
+	a.Equal("ann", lead.Name)
+	a.True(org.Team.Lead != lead)
+	a.Equal("ann", binder.OldValue("Team").(*TestTeam).Lead.Name)
+	a.Equal([]string{"team.lead.name"}, binder.FieldMask())
+}
+
+func (a *TestSuite) TestTxnConfig() {
+	config := DefaultConfig()
+	config.Strict = true
+	tx := NewDGraphTxn(a.DBCon)
+	tx.Config = &config
+	defer tx.Discard(a.Context)
+
+	var user TestModel
+	binder := NewModelBinder(tx, a.Context, &user)
+	err := binder.Set("Start_at", "not a time", true)
+	a.True(err != nil)
+
+	//default txn is not strict
+	binder = NewModelBinder(a.Tx, a.Context, &user)
+	a.NoError(binder.Set("Start_at", "not a time", true))
+
+	config.TimeParser = func(val string) (time.Time, error) {
+		return time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), nil
+	}
+	ctx := WithConfig(a.Context, config)
+	binder = NewModelBinder(a.Tx, ctx, &user)
+	a.NoError(binder.Set("Start_at", "2nd of january", true))
+	a.Equal(2020, user.Start_at.Year())
+}
+
+func (a *TestSuite) TestBinderRedaction() {
+	tx := a.Tx
+	var user TestModel
+	binder := NewModelBinder(tx, a.Context, &user)
+	a.NoError(binder.Set("RegistrationCode", "secret-code", true))
+	a.NoError(binder.Set("Name", "aaa", true))
+
+	changes := binder.RedactedChanges()
+	a.Equal(RedactedValue, changes["RegistrationCode"][1])
+	a.Equal("aaa", changes["Name"][1])
+	a.Equal("secret-code", binder.Changes["RegistrationCode"][1])
+
+	hash := RedactHash(&user, map[string]interface{}{"registration_code": "secret-code", "name": "aaa", "password": GENERIC_PASSWORD})
+	a.Equal(RedactedValue, hash["registration_code"])
+	a.Equal(RedactedValue, hash["password"])
+	a.Equal("aaa", hash["name"])
+
+	err := binder.Sets(map[string]interface{}{"RegistrationCode": 12345}, true)
+	a.True(err != nil)
+	a.False(strings.Contains(err.Error(), "12345"), err.Error())
+	a.True(strings.Contains(err.Error(), RedactedValue), err.Error())
+
+	//nested relations, slices and maps are redacted
+	accounts := []*TestAccount{{Name: "ann", Password: "ann-pass"}, {Name: "bob", Password: "bob-pass"}}
+	logged := fmt.Sprintf("%#v", Redacted(map[string]interface{}{"accounts": accounts, "password": "map-pass"}))
+	a.False(strings.Contains(logged, "-pass"), logged)
+	a.True(strings.Contains(logged, "ann"), logged)
+	rows := Redacted(accounts).([]interface{})
+	a.Equal(RedactedValue, rows[1].(map[string]interface{})["secret"])
+}
+
+func (a *TestSuite) TestRunInTxnRetry() {
+	var role TestRole
+	binder := NewModelBinder(a.Tx, a.Context, &role)
+	binder.Set("Name", "admin", true)
+	_, err := SaveBinder(a.Tx, a.Context, &binder)
+	a.NoError(err)
+	a.MustCommitTx()
+
+	attempts := 0
+	err = RunInTxn(a.Context, a.DBClient, func(tx *DGraphTxn) error {
+		attempts++
+		if _, err := tx.MutateField(a.Context, role.UID, "name", "from retry", false); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			//conflicting write committed first, our commit aborts
+			other := NewDGraphTxnFromClient(a.DBClient)
+			if _, err := other.MutateField(a.Context, role.UID, "name", "from other", true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, TxnOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	a.NoError(err)
+	a.Equal(2, attempts)
+
+	ctx, cancel := context.WithCancel(a.Context)
+	cancel()
+	err = RunInTxn(ctx, a.DBClient, func(tx *DGraphTxn) error {
+		return nil
+	})
+	a.True(errors.Is(err, context.Canceled), "%+v", err)
+}
+
+func (a *TestSuite) TestReadOnlyTxn() {
+	tx := NewReadOnlyDGraphTxnFromClient(a.DBClient)
+	_, err := tx.MutateField(a.Context, "_:role", "name", "admin", false)
+	a.True(errors.Is(err, ErrReadOnlyTxn), "%+v", err)
+
+	var role TestRole
+	binder := NewModelBinder(tx, a.Context, &role)
+	binder.Set("Name", "admin", true)
+	_, err = SaveBinder(tx, a.Context, &binder)
+	a.True(errors.Is(err, ErrReadOnlyTxn), "%+v", err)
+	a.Equal("", role.UID)
+
+	_, err = tx.QueryWithVars(a.Context, `{ q(func: has(name), first: 1) { uid } }`, map[string]string{})
+	a.NoError(err)
+	a.NoError(tx.Commit(a.Context))
+
+	operation, err := GraphQLOperationType(`{ roles { name } }`, "")
+	a.NoError(err)
+	a.Equal("query", operation)
+
+	doc := `
+	# mutation comment { }
+	query list($filter: RoleFilter = {name: "x"}) { roles(filter: $filter) { name } }
+	mutation save { addRole(input: [{name: "}"}]) { numUids } }
+	fragment f on Role { name }`
+	operation, err = GraphQLOperationType(doc, "save")
+	a.NoError(err)
+	a.Equal("mutation", operation)
+	operation, err = GraphQLOperationType(doc, "list")
+	a.NoError(err)
+	a.Equal("query", operation)
+	_, err = GraphQLOperationType(doc, "")
+	a.Error(err)
+
+	tx, err = NewDGraphTxnForGraphQL(a.DBClient, doc, "list", true)
+	a.NoError(err)
+	a.True(tx.ReadOnly)
+	a.True(tx.BestEffort)
+	tx, err = NewDGraphTxnForGraphQL(a.DBClient, doc, "save", false)
+	a.NoError(err)
+	a.False(tx.ReadOnly)
+	a.NoError(tx.Discard(a.Context))
+}
+
+func (a *TestSuite) TestUpsert() {
+	query := `query q($name: string) { q(func: eq(name, $name)) @filter(eq(_type, "test_roles")) { v as uid } }`
+	vars := map[string]string{"$name": "upsert-role"}
+	mu := &api.Mutation{SetJson: []byte(`{"uid": "_:new", "name": "upsert-role", "_type": "test_roles"}`), Cond: "@if(eq(len(v), 0))"}
+
+	resp, err := a.Tx.Upsert(a.Context, query, vars, mu)
+	a.NoError(err)
+	a.NotEmpty(resp.Uids["new"])
+	resp, err = a.Tx.Upsert(a.Context, query, vars, mu)
+	a.NoError(err)
+	a.Empty(resp.Uids["new"])
+	a.MustCommitTx()
+
+	var role TestRole
+	created, _, err := SaveIfNotExist(a.Tx, a.Context, &role, map[string]interface{}{"name": "upsert-role"},
+		`eq(name, "upsert-role")`, map[string]QVar{}).Exec()
+	a.NoError(err)
+	a.False(created)
+	a.NotEmpty(role.UID)
+
+	var other TestRole
+	created, _, err = SaveIfNotExist(a.Tx, a.Context, &other, map[string]interface{}{"name": "upsert-other"},
+		`eq(name, "upsert-other")`, map[string]QVar{}).Exec()
+	a.NoError(err)
+	a.True(created)
+	a.NotEmpty(other.UID)
+
+	schema, err := ModelSchema(&TestUniqueRole{})
+	a.NoError(err)
+	a.Equal("name: string @index(exact) @upsert .\nrank: int .", schema)
+}
+
+type TestUniqueRole struct {
+	UID  string `json:"uid,omitempty"`
+	Name string `json:"name,omitempty" dgraph:"upsert"`
+	Rank int    `json:"rank"`
+}
+
+func (a *TestSuite) TestSaveBinders() {
+	roles := make([]TestRole, 3)
+	binders := []*ModelBinder{}
+	for i := range roles {
+		binder := NewModelBinder(a.Tx, a.Context, &roles[i])
+		binder.Set("Name", fmt.Sprintf("batch-%d", i), true)
+		binders = append(binders, &binder)
+	}
+	verrs, err := SaveBinders(a.Tx, a.Context, binders...)
+	a.NoError(err)
+	a.Len(verrs, 3)
+	uids := map[string]bool{}
+	for _, role := range roles {
+		a.NotEmpty(role.UID)
+		uids[role.UID] = true
+	}
+	a.Len(uids, 3)
+
+	config := DefaultConfig()
+	config.SetCreatedUpdatedTimeOnSave = false
+	ctx := WithConfig(a.Context, config)
+
+	var doc TestDocument
+	docBinder := NewModelBinder(a.Tx, ctx, &doc)
+	docBinder.Set("Title", "draft", true)
+	_, err = SaveBinder(a.Tx, ctx, &docBinder)
+	a.NoError(err)
+	tx := a.MustCommitTx()
+	editorA := doc
+	editorB := doc
+
+	binderA := NewModelBinder(tx, ctx, &editorA)
+	binderA.Set("Title", "by a", true)
+	roleBinder := NewModelBinder(tx, ctx, &roles[0])
+	roleBinder.Set("Name", "batch-renamed", true)
+	_, err = SaveBinders(tx, ctx, &binderA, &roleBinder)
+	a.NoError(err)
+	a.Equal(2, editorA.Version)
+
+	binderB := NewModelBinder(tx, ctx, &editorB)
+	binderB.Set("Title", "by b", true)
+	verrs, err = SaveBinders(tx, ctx, &binderB)
+	a.True(errors.Is(err, ErrStaleObject), "expected stale: %+v", err)
+	a.Len(verrs, 1)
+	a.Equal(1, editorB.Version)
+
+	//failed request leaves every model as it was
+	var pending TestAccount
+	pendingBinder := NewModelBinder(tx, ctx, &pending)
+	pendingBinder.Set("Name", "pending", true)
+	pendingBinder.Set("Password", "pending-pass", true)
+	binderB = NewModelBinder(tx, ctx, &editorB)
+	binderB.Set("Title", "by b", true)
+	_, err = SaveBinders(tx, ctx, &pendingBinder, &binderB)
+	a.True(errors.Is(err, ErrStaleObject), "expected stale: %+v", err)
+	a.Equal("", pending.UID)
+	a.Equal("pending-pass", pending.Password)
+	a.Equal(1, editorB.Version)
+}
+
+func (a *TestSuite) TestSaveBindersPasswordAndAudit() {
+	config := DefaultConfig()
+	config.SetCreatedUpdatedTimeOnSave = false
+	config.AuditTrail = true
+	ctx := WithConfig(a.Context, config)
+
+	accounts := make([]TestAccount, 2)
+	binders := []*ModelBinder{}
+	for i := range accounts {
+		binder := NewModelBinder(a.Tx, ctx, &accounts[i])
+		binder.Set("Name", fmt.Sprintf("batch-account-%d", i), true)
+		binder.Set("Password", fmt.Sprintf("batch-pass-%d", i), true)
+		binders = append(binders, &binder)
+	}
+	_, err := SaveBinders(a.Tx, ctx, binders...)
+	a.NoError(err)
+	a.MustCommitTx()
+
+	for i, account := range accounts {
+		a.NotEmpty(account.UID)
+		a.Equal("", account.Password)
+		valid, err := CheckPassword(a.Tx, ctx, account.UID, "secret", fmt.Sprintf("batch-pass-%d", i))
+		a.NoError(err)
+		a.True(valid)
+
+		history, err := AuditHistory(a.Tx, ctx, account.UID)
+		a.NoError(err)
+		a.Len(history, 1)
+		a.Equal("create", history[0].Action)
+	}
+}
+
+type testRank uint16
+
+func (a *TestSuite) TestMutateFieldValueTypes() {
+	var role TestRole
+	binder := NewModelBinder(a.Tx, a.Context, &role)
+	binder.Set("Name", "typed", true)
+	_, err := SaveBinder(a.Tx, a.Context, &binder)
+	a.NoError(err)
+
+	published := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	_, err = a.Tx.MutateField(a.Context, role.UID, "tags", []string{"a", "b", "c"}, false)
+	a.NoError(err)
+	_, err = a.Tx.MutateField(a.Context, role.UID, "rank", testRank(7), false)
+	a.NoError(err)
+	_, err = a.Tx.MutateField(a.Context, role.UID, "published_at", &published, false)
+	a.NoError(err)
+	_, err = a.Tx.MutateField(a.Context, role.UID, "rank", struct{}{}, false)
+	a.Error(err)
+
+	resp, err := a.Tx.QueryWithVars(a.Context, `query q($id: string) {
+		q(func: uid($id)) { tags rank published_at }
+	}`, map[string]string{"$id": role.UID})
+	a.NoError(err)
+	var result struct {
+		Q []struct {
+			Tags        []string  `json:"tags"`
+			Rank        int       `json:"rank"`
+			PublishedAt time.Time `json:"published_at"`
+		} `json:"q"`
+	}
+	a.NoError(json.Unmarshal(resp.Json, &result))
+	a.Len(result.Q, 1)
+	a.ElementsMatch([]string{"a", "b", "c"}, result.Q[0].Tags)
+	a.Equal(7, result.Q[0].Rank)
+	a.True(published.Equal(result.Q[0].PublishedAt))
+}
+
+type TestFacetRole struct {
+	UID    string    `json:"uid"`
+	Name   string    `json:"name"`
+	Since  time.Time `facet:"test_roles|since"`
+	Weight float64   `facet:"test_roles|weight"`
+}
+
+type TestMember struct {
+	UID       string          `json:"uid"`
+	Name      string          `json:"name"`
+	TestRoles []TestFacetRole `json:"test_roles"`
+}
+
+func (a *TestSuite) TestEdgeFacets() {
+	var member, role TestRole
+	memberBinder := NewModelBinder(a.Tx, a.Context, &member)
+	memberBinder.Set("Name", "member", true)
+	_, err := SaveBinder(a.Tx, a.Context, &memberBinder)
+	a.NoError(err)
+	roleBinder := NewModelBinder(a.Tx, a.Context, &role)
+	roleBinder.Set("Name", "editor", true)
+	_, err = SaveBinder(a.Tx, a.Context, &roleBinder)
+	a.NoError(err)
+
+	since := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	_, err = a.Tx.AssociateWithFacets(a.Context, member.UID, "test_roles", role.UID,
+		map[string]interface{}{"since": since, "weight": 0.5}, false)
+	a.NoError(err)
+
+	a.Equal("@facets(since, weight)", FacetsDirective([]TestFacetRole{}, "test_roles"))
+	resp, err := a.Tx.QueryWithVars(a.Context, `query q($id: string) {
+		q(func: uid($id)) { uid name test_roles @facets(since, weight) { uid name } }
+	}`, map[string]string{"$id": member.UID})
+	a.NoError(err)
+
+	var result struct {
+		Q []TestMember `json:"q"`
+	}
+	a.NoError(UnmarshalWithFacets(a.Context, resp.Json, &result))
+	a.Len(result.Q, 1)
+	a.Len(result.Q[0].TestRoles, 1)
+	a.Equal("editor", result.Q[0].TestRoles[0].Name)
+	a.True(since.Equal(result.Q[0].TestRoles[0].Since))
+	a.Equal(0.5, result.Q[0].TestRoles[0].Weight)
+}
+
+func (a *TestSuite) TestSaveBinderEdgeFacets() {
+	var role TestRole
+	roleBinder := NewModelBinder(a.Tx, a.Context, &role)
+	roleBinder.Set("Name", "reviewer", true)
+	_, err := SaveBinder(a.Tx, a.Context, &roleBinder)
+	a.NoError(err)
+
+	var member TestFacetMember
+	binder := NewModelBinder(a.Tx, a.Context, &member)
+	binder.Set("Name", "facet member", true)
+	a.NoError(binder.Set("TestRoles", []interface{}{
+		map[string]interface{}{"uid": role.UID, "name": "reviewer"},
+	}, true))
+	since := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	member.TestRoles[0].Since = since
+	member.TestRoles[0].Weight = 0.25
+	_, err = SaveBinder(a.Tx, a.Context, &binder)
+	a.NoError(err)
+	a.NotEmpty(member.UID)
+
+	resp, err := a.Tx.QueryWithVars(a.Context, fmt.Sprintf(`query q($id: string) {
+		q(func: uid($id)) { uid name test_roles %s { uid name } }
+	}`, FacetsDirective(member.TestRoles, "test_roles")), map[string]string{"$id": member.UID})
+	a.NoError(err)
+
+	var result struct {
+		Q []TestFacetMember `json:"q"`
+	}
+	a.NoError(UnmarshalWithFacets(a.Context, resp.Json, &result))
+	a.Len(result.Q, 1)
+	a.Len(result.Q[0].TestRoles, 1)
+	a.Equal(role.UID, result.Q[0].TestRoles[0].UID)
+	a.True(since.Equal(result.Q[0].TestRoles[0].Since))
+	a.Equal(0.25, result.Q[0].TestRoles[0].Weight)
+}
+
+type TestFacetMember struct {
+	UID       string          `json:"uid"`
+	Name      string          `json:"name"`
+	TestRoles []TestFacetRole `json:"test_roles"`
+}
+
+func (t TestFacetMember) TableName() string {
+	return "test_members"
+}
+
+type TestProduct struct {
+	UID       string          `json:"uid"`
+	Name      LocalizedString `json:"product_name"`
+	TaglineEn string          `json:"tagline" lang:"en"`
+}
+
+func (t TestProduct) TableName() string {
+	return "test_products"
+}
+
+func (a *TestSuite) TestLocalizedString() {
+	config := DefaultConfig()
+	config.SetCreatedUpdatedTimeOnSave = false
+	ctx := WithConfig(a.Context, config)
+
+	var product TestProduct
+	binder := NewModelBinder(a.Tx, ctx, &product)
+	binder.Set("Name", LocalizedString{"en": "Durian cake", "ms": "Kek durian"}, true)
+	binder.Set("TaglineEn", "King of fruits", true)
+	_, err := SaveBinder(a.Tx, ctx, &binder)
+	a.NoError(err)
+
+	_, err = a.Tx.MutateFieldLang(ctx, product.UID, "tagline", "ms", "Raja buah", false)
+	a.NoError(err)
+
+	ctx = WithLanguages(ctx, "zh", "ms")
+	a.Equal("product_name@zh:ms:.", LangSelector(ctx, "product_name"))
+	resp, err := a.Tx.QueryWithVars(ctx, `query q($id: string) {
+		q(func: uid($id)) { uid product_name@en product_name@ms tagline@en tagline@ms }
+	}`, map[string]string{"$id": product.UID})
+	a.NoError(err)
+
+	var result struct {
+		Q []TestProduct `json:"q"`
+	}
+	a.NoError(UnmarshalWithLanguages(ctx, resp.Json, &result))
+	a.Len(result.Q, 1)
+	a.Equal("Kek durian", result.Q[0].Name["ms"])
+	a.Equal("Kek durian", result.Q[0].Name.In(ctx))
+	a.Equal("Durian cake", result.Q[0].Name.Get("en"))
+	a.Equal("King of fruits", result.Q[0].TaglineEn)
+
+	resp, err = a.Tx.QueryWithVars(ctx, fmt.Sprintf(`query q($id: string) {
+		q(func: uid($id)) { uid %s }
+	}`, LangSelector(ctx, "product_name")), map[string]string{"$id": product.UID})
+	a.NoError(err)
+	a.NoError(UnmarshalWithLanguages(ctx, resp.Json, &result))
+	a.Equal("Kek durian", result.Q[0].Name.Get())
+}
+
+type TestShop struct {
+	UID      string `json:"uid"`
+	Name     string `json:"name"`
+	Location Geo    `json:"location"`
+	Area     *Geo   `json:"area"`
+}
+
+func (t TestShop) TableName() string {
+	return "test_shops"
+}
+
+func (a *TestSuite) TestGeo() {
+	config := DefaultConfig()
+	config.SetCreatedUpdatedTimeOnSave = false
+	ctx := WithConfig(a.Context, config)
+
+	var shop TestShop
+	binder := NewModelBinder(a.Tx, ctx, &shop)
+	a.NoError(binder.Set("Location", "3.1390,101.6869", true))
+	lat, lng, err := shop.Location.LatLng()
+	a.NoError(err)
+	a.Equal(3.1390, lat)
+	a.Equal(101.6869, lng)
+	a.NoError(binder.Set("Area", map[string]interface{}{
+		"type":        "Polygon",
+		"coordinates": []interface{}{[]interface{}{[]interface{}{101.6, 3.1}, []interface{}{101.7, 3.1}, []interface{}{101.7, 3.2}, []interface{}{101.6, 3.1}}},
+	}, true))
+	a.Equal(GeoPolygon, shop.Area.Type)
+	a.Error(binder.Set("Location", "not a point", true))
+	binder.Set("Location", NewPoint(3.1390, 101.6869), true)
+	binder.Set("Name", "kl shop", true)
+	_, err = SaveBinder(a.Tx, ctx, &binder)
+	a.NoError(err)
+	a.MustCommitTx()
+
+	near, err := GeoNear("location", NewPoint(3.14, 101.687), 1000)
+	a.NoError(err)
+	a.Equal("near(location, [101.687,3.14], 1000)", near)
+	within, err := GeoWithin("location", *shop.Area)
+	a.NoError(err)
+	_, err = GeoWithin("location", shop.Location)
+	a.Error(err)
+	_, err = GeoNear("location, 1, 1) or has(password", NewPoint(3.14, 101.687), 1000)
+	a.Error(err)
+	_, err = GeoWithin("location) or has(password", *shop.Area)
+	a.Error(err)
+
+	for _, filter := range []string{near, within} {
+		resp, err := a.Tx.QueryWithVars(ctx, fmt.Sprintf(`{
+			q(func: %s) { uid name location }
+		}`, filter), map[string]string{})
+		a.NoError(err)
+		var result struct {
+			Q []TestShop `json:"q"`
+		}
+		a.NoError(json.Unmarshal(resp.Json, &result))
+		a.Len(result.Q, 1, filter)
+		a.Equal(shop.UID, result.Q[0].UID)
+		a.Equal(shop.Location, result.Q[0].Location)
+	}
+
+	schema, err := ModelSchema(&TestShop{})
+	a.NoError(err)
+	a.Equal("area: geo @index(geo) .\nlocation: geo @index(geo) .\nname: string .", schema)
+}
+
+type TestAccount struct {
+	UID      string `json:"uid"`
+	Name     string `json:"name"`
+	Password string `json:"secret" dgraph:"password"`
+}
+
+func (t TestAccount) TableName() string {
+	return "test_accounts"
+}
+
+func (a *TestSuite) TestPasswordField() {
+	config := DefaultConfig()
+	config.SetCreatedUpdatedTimeOnSave = false
+	ctx := WithConfig(a.Context, config)
+
+	var account TestAccount
+	binder := NewModelBinder(a.Tx, ctx, &account)
+	binder.Set("Name", "alice", true)
+	binder.Set("Password", "s3cret-pass", true)
+	a.Equal(RedactedValue, binder.RedactedChanges()["Password"][1])
+	_, err := SaveBinder(a.Tx, ctx, &binder)
+	a.NoError(err)
+	a.NotEmpty(account.UID)
+	a.Equal("", account.Password)
+	a.True(binder.Changed("Password"))
+	state, err := json.Marshal(&binder)
+	a.NoError(err)
+	a.NotContains(string(state), "s3cret-pass")
+	a.MustCommitTx()
+
+	valid, err := CheckPassword(a.Tx, ctx, account.UID, "secret", "s3cret-pass")
+	a.NoError(err)
+	a.True(valid)
+	valid, err = CheckPassword(a.Tx, ctx, account.UID, "secret", "wrong-pass")
+	a.NoError(err)
+	a.False(valid)
+	_, err = CheckPassword(a.Tx, ctx, account.UID, "secret) { uid }", "x")
+	a.Error(err)
+
+	//update changes the password in the same mutation
+	binder = NewModelBinder(a.Tx, ctx, &account)
+	binder.Set("Password", "new-pass", true)
+	_, err = SaveBinder(a.Tx, ctx, &binder)
+	a.NoError(err)
+	valid, err = CheckPassword(a.Tx, ctx, account.UID, "secret", "new-pass")
+	a.NoError(err)
+	a.True(valid)
+
+	schema, err := ModelSchema(&TestAccount{})
+	a.NoError(err)
+	a.Contains(schema, "secret: password .")
+	a.NoError(UpdateSchema(a.Tx, ctx, &TestAccount{}, false))
+}
+
+func (a *TestSuite) TestOperationTimeouts() {
+	config := DefaultConfig()
+	config.QueryTimeout = time.Nanosecond
+	tx := NewDGraphTxn(a.DBCon)
+	tx.Config = &config
+	defer tx.Discard(a.Context)
+
+	q := `{ q(func: has(name), first: 1) { uid } }`
+	_, err := tx.QueryWithVars(a.Context, q, map[string]string{})
+	a.True(errors.Is(err, ErrTimeout), "%+v", err)
+	var timeoutErr *TimeoutError
+	a.True(errors.As(err, &timeoutErr))
+	a.Equal("query", timeoutErr.Op)
+	a.Equal(time.Nanosecond, timeoutErr.Timeout)
+
+	//deadline of caller wins over default timeout
+	ctx, cancel := context.WithTimeout(a.Context, 10*time.Second)
+	defer cancel()
+	_, err = tx.QueryWithVars(ctx, q, map[string]string{})
+	a.NoError(err)
+
+	ctx, cancel = context.WithCancel(a.Context)
+	cancel()
+	err = UpdateSchema(a.Tx, ctx, &TestRole{}, false)
+	a.True(errors.Is(err, context.Canceled), "%+v", err)
+	a.False(errors.Is(err, ErrTimeout))
+}
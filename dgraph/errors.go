@@ -0,0 +1,40 @@
+package dgraph
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// stored version of the node no longer matches the loaded version, reload and retry
+var ErrStaleObject = errors.New("stale object")
+
+// mutation attempted on a read-only (or best-effort) transaction
+var ErrReadOnlyTxn = errors.New("read-only transaction")
+
+// every endpoint of a ClientManager failed its last health check
+var ErrNoHealthyEndpoint = errors.New("no healthy dgraph endpoint")
+
+// dgraph operation exceeded its deadline, errors.Is(err, ErrTimeout) matches any TimeoutError
+var ErrTimeout = errors.New("dgraph timeout")
+
+type TimeoutError struct {
+	Op      string        // query, mutate, commit or schema
+	Timeout time.Duration // default timeout applied, 0 when deadline came from caller context
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Timeout == 0 {
+		return fmt.Sprintf("dgraph %s exceeded context deadline: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("dgraph %s timed out after %s: %v", e.Op, e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
@@ -0,0 +1,262 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v2/protos/api"
+)
+
+// create relation from "id" to "relatedID" with edge facets, example: {"since": time.Now(), "weight": 0.5}
+func (d *DGraphTxn) AssociateWithFacets(ctx context.Context, id string, relation string, relatedID string, facets map[string]interface{}, commit bool) (*api.Response, error) {
+	val, err := WithFacets(&api.NQuad{Subject: id, Predicate: relation, ObjectId: relatedID}, facets)
+	if err != nil {
+		return &api.Response{}, err
+	}
+	return d.MutateSet(ctx, []*api.NQuad{val}, commit)
+}
+
+// attach facets to nquad, for MutateSet
+func WithFacets(nquad *api.NQuad, facets map[string]interface{}) (*api.NQuad, error) {
+	apiFacets, err := Facets(facets)
+	if err != nil {
+		return nquad, err
+	}
+	nquad.Facets = append(nquad.Facets, apiFacets...)
+	return nquad, nil
+}
+
+// encode facets as dgraph typed facets, sorted by key
+func Facets(facets map[string]interface{}) ([]*api.Facet, error) {
+	keys := make([]string, 0, len(facets))
+	for key := range facets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := []*api.Facet{}
+	for _, key := range keys {
+		facet, err := facetValue(key, facets[key])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, facet)
+	}
+	return result, nil
+}
+
+func facetValue(key string, value interface{}) (*api.Facet, error) {
+	if t, ok := value.(time.Time); ok {
+		data, err := t.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return &api.Facet{Key: key, Value: data, ValType: api.Facet_DATETIME}, nil
+	}
+
+	val := reflect.ValueOf(value)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, fmt.Errorf("Facet %s is nil", key)
+		}
+		return facetValue(key, val.Elem().Interface())
+	}
+
+	data := make([]byte, 8)
+	switch val.Kind() {
+	case reflect.String:
+		return &api.Facet{Key: key, Value: []byte(val.String()), ValType: api.Facet_STRING}, nil
+	case reflect.Bool:
+		if val.Bool() {
+			return &api.Facet{Key: key, Value: []byte{1}, ValType: api.Facet_BOOL}, nil
+		}
+		return &api.Facet{Key: key, Value: []byte{0}, ValType: api.Facet_BOOL}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		binary.LittleEndian.PutUint64(data, uint64(val.Int()))
+		return &api.Facet{Key: key, Value: data, ValType: api.Facet_INT}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("Facet %s overflows int64: %d", key, val.Uint())
+		}
+		binary.LittleEndian.PutUint64(data, val.Uint())
+		return &api.Facet{Key: key, Value: data, ValType: api.Facet_INT}, nil
+	case reflect.Float32, reflect.Float64:
+		binary.LittleEndian.PutUint64(data, math.Float64bits(val.Float()))
+		return &api.Facet{Key: key, Value: data, ValType: api.Facet_FLOAT}, nil
+	}
+	return nil, fmt.Errorf("Unsupported facet type %s: %T", key, value)
+}
+
+// facets of a graphql input row, fields tagged `facet:"since"` or `facet:"roles|since"`
+// nil pointers are skipped
+func edgeFacets(row reflect.Value, predicate string) ([]*api.Facet, error) {
+	row = reflect.Indirect(row)
+	if !row.IsValid() || row.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	facets := map[string]interface{}{}
+	for i := 0; i < row.NumField(); i++ {
+		tag := row.Type().Field(i).Tag.Get("facet")
+		if tag == "" {
+			continue
+		}
+		field := row.Field(i)
+		if field.Kind() == reflect.Ptr && field.IsNil() {
+			continue
+		}
+		key := tag
+		if parts := strings.SplitN(tag, "|", 2); len(parts) == 2 {
+			if parts[0] != predicate {
+				continue
+			}
+			key = parts[1]
+		}
+		facets[key] = field.Interface()
+	}
+	return Facets(facets)
+}
+
+// field holding a facet of the edge to its model, not a predicate of the node
+func isFacetField(field reflect.StructField) bool {
+	return field.Tag.Get("facet") != ""
+}
+
+// relation of a model as json mutation edges carrying the facets declared on its rows as
+// `facet:"predicate|key"`, example: [{"uid": "0x2", "test_roles|since": ...}]
+// ok false when the row type declares no facet of predicate, rows without uid are not written
+func edgeFacetRows(relation reflect.Value, predicate string) ([]map[string]interface{}, bool) {
+	if !relation.IsValid() || relation.Kind() == reflect.Interface || FacetsDirective(relation.Interface(), predicate) == "" {
+		return nil, false
+	}
+	relation = reflect.Indirect(relation)
+	rows := []reflect.Value{}
+	switch relation.Kind() {
+	case reflect.Slice:
+		for i := 0; i < relation.Len(); i++ {
+			rows = append(rows, reflect.Indirect(relation.Index(i)))
+		}
+	case reflect.Struct:
+		rows = append(rows, relation)
+	}
+
+	edges := []map[string]interface{}{}
+	for _, row := range rows {
+		if !row.IsValid() || row.FieldByName("UID").String() == "" {
+			continue
+		}
+		edge := map[string]interface{}{"uid": row.FieldByName("UID").String()}
+		for i := 0; i < row.NumField(); i++ {
+			parts := strings.SplitN(row.Type().Field(i).Tag.Get("facet"), "|", 2)
+			if len(parts) != 2 || parts[0] != predicate {
+				continue
+			}
+			field := row.Field(i)
+			if field.Kind() == reflect.Ptr && field.IsNil() {
+				continue
+			}
+			edge[predicate+"|"+parts[1]] = field.Interface()
+		}
+		edges = append(edges, edge)
+	}
+	return edges, true
+}
+
+// @facets(...) directive selecting facets of predicate declared on model fields as `facet:"predicate|key"`
+// empty when model has no facet of predicate
+func FacetsDirective(model interface{}, predicate string) string {
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Ptr || modelType.Kind() == reflect.Slice {
+		modelType = modelType.Elem()
+	}
+	keys := []string{}
+	for i := 0; i < modelType.NumField(); i++ {
+		parts := strings.SplitN(modelType.Field(i).Tag.Get("facet"), "|", 2)
+		if len(parts) == 2 && parts[0] == predicate {
+			keys = append(keys, parts[1])
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("@facets(%s)", strings.Join(keys, ", "))
+}
+
+// json.Unmarshal query response into v, then BindFacets
+func UnmarshalWithFacets(ctx context.Context, data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	return BindFacets(ctx, v, raw)
+}
+
+// set fields tagged `facet:"predicate|key"` from "predicate|key" of decoded query response,
+// walking relations by json name
+func BindFacets(ctx context.Context, model interface{}, raw interface{}) error {
+	return bindFacets(ctx, reflect.ValueOf(model), raw)
+}
+
+func bindFacets(ctx context.Context, val reflect.Value, raw interface{}) error {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Slice:
+		rows, ok := raw.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i := 0; i < val.Len() && i < len(rows); i++ {
+			if err := bindFacets(ctx, val.Index(i), rows[i]); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if val.Type().String() == "time.Time" {
+			return nil
+		}
+		//single relation returned as list of 1
+		if rows, ok := raw.([]interface{}); ok && len(rows) == 1 {
+			raw = rows[0]
+		}
+		row, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for i := 0; i < val.NumField(); i++ {
+			stField := val.Type().Field(i)
+			if stField.PkgPath != "" {
+				continue
+			}
+			field := val.Field(i)
+			if tag := stField.Tag.Get("facet"); tag != "" {
+				if value, has := row[tag]; has && value != nil {
+					if err := BindFieldValue(ctx, stField.Name, &field, reflect.ValueOf(value)); err != nil {
+						return fmt.Errorf("Unable to bind facet %s: %+v", tag, err)
+					}
+				}
+				continue
+			}
+			if nested, has := row[stField.Tag.Get("json")]; has {
+				if err := bindFacets(ctx, field, nested); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package dgraph
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"github.com/dgraph-io/dgo/v2/protos/api"
+	geom "github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/wkb"
+)
+
+// geo as dgraph geo value (well known binary)
+func geoApiValue(g Geo) (*api.Value, error) {
+	var geometry geom.T
+	switch g.Type {
+	case GeoPoint:
+		lat, lng, err := g.LatLng()
+		if err != nil {
+			return &api.Value{}, err
+		}
+		geometry = geom.NewPointFlat(geom.XY, []float64{lng, lat})
+	case GeoPolygon:
+		rings, err := g.Rings()
+		if err != nil {
+			return &api.Value{}, err
+		}
+		coords := make([][]geom.Coord, len(rings))
+		for i, ring := range rings {
+			for _, point := range ring {
+				coords[i] = append(coords[i], geom.Coord(point))
+			}
+		}
+		polygon, err := geom.NewPolygon(geom.XY).SetCoords(coords)
+		if err != nil {
+			return &api.Value{}, err
+		}
+		geometry = polygon
+	default:
+		return &api.Value{}, fmt.Errorf("Unsupported geo type: %s", g.Type)
+	}
+
+	data, err := wkb.Marshal(geometry, binary.LittleEndian)
+	if err != nil {
+		return &api.Value{}, err
+	}
+	return &api.Value{Val: &api.Value_GeoVal{data}}, nil
+}
+
+// filter of nodes within meters of point, example: Q(tx).Where(GeoNear("location", point, 1000), ...)
+func GeoNear(predicate string, point Geo, meters float64) (string, error) {
+	if !predicatePattern.MatchString(predicate) {
+		return "", fmt.Errorf("Invalid predicate: %s", predicate)
+	}
+	if _, _, err := point.LatLng(); err != nil {
+		return "", err
+	}
+	coords, err := point.DQL()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("near(%s, %s, %v)", predicate, coords, meters), nil
+}
+
+// filter of nodes located within polygon
+func GeoWithin(predicate string, polygon Geo) (string, error) {
+	return geoFilter("within", predicate, polygon, GeoPolygon)
+}
+
+// filter of nodes (polygons) containing point or polygon
+func GeoContains(predicate string, geo Geo) (string, error) {
+	return geoFilter("contains", predicate, geo, "")
+}
+
+// filter of nodes (polygons) intersecting polygon
+func GeoIntersects(predicate string, polygon Geo) (string, error) {
+	return geoFilter("intersects", predicate, polygon, GeoPolygon)
+}
+
+func geoFilter(function string, predicate string, geo Geo, geoType string) (string, error) {
+	if !predicatePattern.MatchString(predicate) {
+		return "", fmt.Errorf("Invalid predicate: %s", predicate)
+	}
+	if geoType != "" && geo.Type != geoType {
+		return "", fmt.Errorf("%s requires %s, got: %s", function, geoType, geo.Type)
+	}
+	coords, err := geo.DQL()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s(%s, %s)", function, predicate, coords), nil
+}
+
+// value of Geo or *Geo field, geo is written as a value, not a relation
+func geoFieldValue(field reflect.Value) (Geo, bool) {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return Geo{}, field.Type().Elem() == reflect.TypeOf(Geo{})
+		}
+		field = field.Elem()
+	}
+	geo, ok := field.Interface().(Geo)
+	return geo, ok
+}
@@ -1,23 +1,29 @@
 package dgraph
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
 
 	_ "github.com/u007/gobinder"
+	"github.com/u007/gobinder/lib"
 
-	"github.com/dgraph-io/dgo/protos/api"
+	"github.com/dgraph-io/dgo/v2/protos/api"
 )
 
 // update relation from arguments, but ensure to have saved this record first
 // args.predicate_id = single string
 // args.predicate = [{id: '0x02'}, {id:'0x03'}] - save multiple
+// args.predicate = [{id: '0x02', since: ...}] - row fields tagged `facet:"since"` are written as edge facets
 // args.predicate_id = "" to delete existing relation
 // args.predicate = [] - to delete all existing relation
 // TODO check for update single relation to see if its same as before, if yes, ignore updatedFields
-func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values interface{}, permitted []string, markChanged bool) error {
-	ctx := this.Context
+func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values interface{}, permitted []string, markChanged bool) (err error) {
+	ctx, span := tx.config(this.Context).StartSpan(this.Context, "binder.UpdateRelationFromGraphQLArgs",
+		lib.Attr("model", reflect.TypeOf(this.Model()).String()), lib.Attr("uid", this.Get("UID")))
+	defer func() { lib.EndSpan(span, err) }()
+
 	model := this.Model()
 	modelVal := reflect.Indirect(reflect.ValueOf(model))
 	val := reflect.Indirect(reflect.ValueOf(values))
@@ -28,6 +34,7 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 		return fmt.Errorf("Invalid id")
 	}
 
+	modelLog := structuredLogging(ctx).With("model", reflect.TypeOf(model).String(), "uid", id)
 	updatedFields := []string{}
 	for c := 0; c < val.NumField(); c++ {
 		name := val.Type().Field(c).Name
@@ -64,7 +71,8 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 			continue //skipping
 		}
 
-		logging(ctx).Debugf("UpdateRelationFromGraphQLArgs %s of model: %s : %s", dbName, reflect.TypeOf(this.Model()), id)
+		log := modelLog.With("predicate", dbName)
+		log.Debugw("UpdateRelationFromGraphQLArgs")
 		for d := 0; d < modelVal.NumField(); d++ {
 			fieldName := modelVal.Type().Field(d).Name
 			fieldType := modelVal.Type().Field(d).Type
@@ -80,14 +88,14 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 			}
 
 			foundField = true
-			logging(ctx).Debugf("Found %s of %s, kind: %s", dbName, id, fieldType.Kind())
+			log.Debugw("found relation", "kind", fieldType.Kind())
 			if fieldType.Kind() == reflect.Struct {
 				// logging(ctx).Debugf("is struct: %s", fieldJsonName)
 				if fieldType.Name() == "Time" {
 					continue //ignoring time struct
 				}
 
-				logging(ctx).Debugf("single relation type: %s", valueField.Type().Name())
+				log.Debugw("single relation", "type", valueField.Type().Name())
 				//single update
 
 				if !nestedRelation {
@@ -126,7 +134,7 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 				continue // skip none struct nor slice
 			}
 
-			logging(ctx).Debugf("Deleting existing %s of %s", dbName, id)
+			log.Debugw("deleting existing relation", "action", "delete")
 			if nestedRelation {
 				//delete actual record, to ensure no hanging relation
 				//query existing relation for id, and delete all object
@@ -136,7 +144,7 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 			} //is nestedRelation
 			//dgraph direct delete existing relationship
 			if _, err := tx.MutateDeleteField(ctx, id, dbName, nil, false); err != nil {
-				logging(ctx).Errorf("unable to delete field: %+v", err)
+				log.Errorw("unable to delete field", "error", err)
 				return err
 			}
 
@@ -159,7 +167,7 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 					//saving single nested struct
 					deleteField := reflect.Indirect(valueField.FieldByName("DELETE_"))
 					if deleteField.IsValid() && deleteField.Interface().(bool) == true {
-						logging(ctx).Debugf("Deleting %s struct, not inserting child", name)
+						log.Debugw("deleting struct, not inserting child", "field", name)
 						break
 					}
 
@@ -167,7 +175,7 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 					childBinder := NewModelBinder(tx, ctx, newChild.Interface())
 					childPermitted := []string{"*"}
 					childBinder.SetsFromGraphQLArgs(valueField.Interface(), childPermitted, true)
-					logging(ctx).Debugf("Saving Child %s %#v", name, newChild.Elem().Interface())
+					structuredLogging(ctx).Debugw("saving child", "field", name, "child", Redacted(newChild.Interface()))
 
 					verrs, err := SaveBinder(tx, ctx, &childBinder)
 					if verrs != nil && verrs.HasAny() {
@@ -178,8 +186,12 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 
 					childID := newChild.Elem().FieldByName("UID")
 					// logging(ctx).Debugf("child: %#v, id: %#v", newChild.Elem(), rowID)
-					logging(ctx).Debugf("relation: %s, %s set: %v", id, dbName, childID.Interface())
-					sets = append(sets, &api.NQuad{Subject: id, Predicate: dbName, ObjectId: childID.Interface().(string)})
+					log.Debugw("relation set", "action", "set", "child_uid", childID.Interface())
+					facets, err := edgeFacets(valueField, dbName)
+					if err != nil {
+						return err
+					}
+					sets = append(sets, &api.NQuad{Subject: id, Predicate: dbName, ObjectId: childID.Interface().(string), Facets: facets})
 				}
 			} // is struct
 
@@ -189,11 +201,15 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 					valueRow := valueField.Index(vI)
 					// logging(ctx).Debugf("child: %s, row: %#v", dbName, valueRow.Interface())
 					rowID := valueRow.FieldByName("ID")
+					facets, err := edgeFacets(valueRow, dbName)
+					if err != nil {
+						return err
+					}
 
 					if rowID.IsValid() && rowID.Interface().(string) != "" {
 						//relates by id
-						logging(ctx).Debugf("relation: %s, %s append: %v", id, dbName, rowID.Interface())
-						sets = append(sets, &api.NQuad{Subject: id, Predicate: dbName, ObjectId: rowID.Interface().(string)})
+						log.Debugw("relation append", "action", "append", "child_uid", rowID.Interface())
+						sets = append(sets, &api.NQuad{Subject: id, Predicate: dbName, ObjectId: rowID.Interface().(string), Facets: facets})
 					} else {
 						//is empty, create nested child
 						childType := fieldType.Elem()
@@ -205,7 +221,7 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 						childBinder := NewModelBinder(tx, ctx, newChild.Interface())
 						childPermitted := []string{"*"}
 						childBinder.SetsFromGraphQLArgs(valueRow.Interface(), childPermitted, true)
-						logging(ctx).Debugf("Saving Child %s %#v", name, newChild.Elem().Interface())
+						structuredLogging(ctx).Debugw("saving child", "field", name, "child", Redacted(newChild.Interface()))
 
 						verrs, err := SaveBinder(tx, ctx, &childBinder)
 						if verrs != nil && verrs.HasAny() {
@@ -216,8 +232,8 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 
 						rowID = newChild.Elem().FieldByName("UID")
 						// logging(ctx).Debugf("child: %#v, id: %#v", newChild.Elem(), rowID)
-						logging(ctx).Debugf("relation: %s, %s append: %v", id, dbName, rowID.Interface())
-						sets = append(sets, &api.NQuad{Subject: id, Predicate: dbName, ObjectId: rowID.Interface().(string)})
+						log.Debugw("relation append", "action", "append", "child_uid", rowID.Interface())
+						sets = append(sets, &api.NQuad{Subject: id, Predicate: dbName, ObjectId: rowID.Interface().(string), Facets: facets})
 					} // is nested child value
 
 				} //each slice of value
@@ -234,7 +250,7 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 
 		} //each model field
 		if !foundField {
-			logging(this.Context).Debugf("ignore missing relation: %s", dbName)
+			log.Debugw("ignore missing relation")
 		}
 		// modelField := modelVal.FieldByName(dbName)
 	} //each values field
@@ -242,7 +258,9 @@ func (this *ModelBinder) UpdateRelationFromGraphQLArgs(tx *DGraphTxn, values int
 	if len(updatedFields) > 0 {
 		hook, ok2 := model.(BaseAfterSaveableRelation)
 		if ok2 {
-			if err := hook.AfterSaveRelation(tx, ctx, this, updatedFields...); err != nil {
+			if err := traceHook(tx, ctx, "AfterSaveRelation", func(ctx context.Context) error {
+				return hook.AfterSaveRelation(tx, ctx, this, updatedFields...)
+			}); err != nil {
 				// gcontext.Logger.Errorf(fmt.Sprintf("postsave error: %+v", err))
 				return err //return original error
 			}
@@ -289,11 +307,11 @@ func (this *ModelBinder) SetsFromGraphQLArgs(values interface{}, permitted []str
 			fieldJsonName := fieldType.Tag.Get("json")
 			// logging(this.Context).Debugf("SetsFromGraphQLArgs-field: %s vs %s", fieldJsonName, dbName)
 			if fieldJsonName == dbName {
-				logging(this.Context).Debugf("field: %s, tag: %#v = %#v", fieldType.Name, fieldType.Tag.Get("json"), field.Interface())
+				structuredLogging(this.Context).Debugw("SetsFromGraphQLArgs", "model", modelVal.Type().String(), "field", fieldType.Name, "predicate", fieldJsonName, "value", this.redactValue(fieldType.Name, field.Interface()))
 				foundField = true
 				// modelField := modelVal.Field(d)
 				if err := this.Set(fieldType.Name, field.Interface(), markChanged); err != nil {
-					err2 := fmt.Errorf("Error setting %s=%+v, error: %+v", fieldType.Name, field, err)
+					err2 := fmt.Errorf("Error setting %s=%+v, error: %+v", fieldType.Name, this.redactValue(fieldType.Name, field.Interface()), err)
 					return err2
 				}
 				break
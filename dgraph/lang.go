@@ -0,0 +1,174 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v2/protos/api"
+)
+
+// write value as predicate@lang, LocalizedString writes one nquad per language
+func (d *DGraphTxn) MutateFieldLang(ctx context.Context, id string, fieldName string, lang string, value interface{}, commit bool) (*api.Response, error) {
+	values := map[string]interface{}{lang: value}
+	if localized, ok := value.(LocalizedString); ok {
+		values = map[string]interface{}{}
+		for lang, text := range localized {
+			values[lang] = text
+		}
+	}
+
+	sets := []*api.NQuad{}
+	for lang, value := range values {
+		apiVal, err := parseAsLangApiValue(value)
+		if err != nil {
+			return &api.Response{}, err
+		}
+		sets = append(sets, &api.NQuad{Subject: id, Predicate: fieldName, ObjectValue: apiVal, Lang: lang})
+	}
+
+	return d.Mutate(ctx, &api.Mutation{
+		Set:       sets,
+		CommitNow: commit,
+	})
+}
+
+// language tagged values must be strings
+func parseAsLangApiValue(value interface{}) (*api.Value, error) {
+	val := reflect.Indirect(reflect.ValueOf(value))
+	if !val.IsValid() || val.Kind() != reflect.String {
+		return &api.Value{}, fmt.Errorf("Language tagged value must be a string: %T", value)
+	}
+	return &api.Value{Val: &api.Value_StrVal{val.String()}}, nil
+}
+
+// hash entries of a field: predicate@lang for `lang:"en"` fields and LocalizedString, otherwise predicate
+func langHashEntries(stField reflect.StructField, value interface{}) map[string]interface{} {
+	jsonName := stField.Tag.Get("json")
+	if localized, ok := value.(LocalizedString); ok {
+		entries := map[string]interface{}{}
+		for lang, text := range localized {
+			entries[langPredicate(jsonName, lang)] = text
+		}
+		return entries
+	}
+	return map[string]interface{}{langPredicate(jsonName, FieldLanguage(stField)): value}
+}
+
+func langPredicate(predicate string, lang string) string {
+	if lang == "" {
+		return predicate
+	}
+	return predicate + "@" + lang
+}
+
+// predicate with language fallback of context, example: name@ms:en:.
+// value is returned under this same key
+func LangSelector(ctx context.Context, predicate string) string {
+	languages := LanguagesFrom(ctx)
+	if len(languages) == 0 {
+		return predicate
+	}
+	return fmt.Sprintf("%s@%s:.", predicate, strings.Join(languages, ":"))
+}
+
+// json.Unmarshal query response into v, then BindLanguages
+func UnmarshalWithLanguages(ctx context.Context, data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	return BindLanguages(ctx, v, raw)
+}
+
+// set `lang:"en"` fields from predicate@en and LocalizedString fields from every predicate@lang
+// of decoded query response, fallback selectors (predicate@ms:en:.) are stored as untagged ""
+func BindLanguages(ctx context.Context, model interface{}, raw interface{}) error {
+	return bindLanguages(ctx, reflect.ValueOf(model), raw)
+}
+
+func bindLanguages(ctx context.Context, val reflect.Value, raw interface{}) error {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Slice:
+		rows, ok := raw.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i := 0; i < val.Len() && i < len(rows); i++ {
+			if err := bindLanguages(ctx, val.Index(i), rows[i]); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if val.Type().String() == "time.Time" {
+			return nil
+		}
+		//single relation returned as list of 1
+		if rows, ok := raw.([]interface{}); ok && len(rows) == 1 {
+			raw = rows[0]
+		}
+		row, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for i := 0; i < val.NumField(); i++ {
+			stField := val.Type().Field(i)
+			if stField.PkgPath != "" {
+				continue
+			}
+			field := val.Field(i)
+			jsonName := stField.Tag.Get("json")
+
+			if IsLocalizedString(stField.Type) {
+				localized := LocalizedString{}
+				for key, value := range row {
+					text, isString := value.(string)
+					if !isString {
+						continue
+					}
+					if key == jsonName {
+						localized[""] = text
+					} else if strings.HasPrefix(key, jsonName+"@") {
+						lang := key[len(jsonName)+1:]
+						if strings.Contains(lang, ":") {
+							lang = ""
+						}
+						localized[lang] = text
+					}
+				}
+				if len(localized) > 0 {
+					field.Set(reflect.ValueOf(localized))
+				}
+				continue
+			}
+
+			if lang := FieldLanguage(stField); lang != "" {
+				if value, has := row[langPredicate(jsonName, lang)]; has && value != nil {
+					if err := BindFieldValue(ctx, stField.Name, &field, reflect.ValueOf(value)); err != nil {
+						return fmt.Errorf("Unable to bind %s@%s: %+v", jsonName, lang, err)
+					}
+				}
+				continue
+			}
+
+			if nested, has := row[jsonName]; has {
+				if err := bindLanguages(ctx, field, nested); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
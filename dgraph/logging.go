@@ -0,0 +1,15 @@
+package dgraph
+
+import (
+	"context"
+
+	"github.com/u007/gobinder/lib"
+)
+
+func logging(ctx context.Context) lib.Logger {
+	return lib.LoggerFrom(ctx)
+}
+
+func structuredLogging(ctx context.Context) lib.StructuredLogger {
+	return lib.StructuredLoggerFrom(ctx)
+}
@@ -0,0 +1,22 @@
+package dgraph
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/dgo/v2"
+	"github.com/dgraph-io/dgo/v2/protos/api"
+)
+
+// record duration, abort and server latency of a dgraph operation
+func observeOperation(config Config, op string, start time.Time, err error, latency *api.Latency) {
+	metrics := config.MetricsRecorder()
+	metrics.ObserveOperation(op, time.Since(start), err)
+	if errors.Is(err, dgo.ErrAborted) {
+		metrics.IncAbort(op)
+	}
+	if latency != nil {
+		metrics.ObserveServerLatency(op,
+			time.Duration(latency.ParsingNs), time.Duration(latency.ProcessingNs), time.Duration(latency.EncodingNs))
+	}
+}
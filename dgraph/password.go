@@ -0,0 +1,80 @@
+package dgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/dgraph-io/dgo/v2/protos/api"
+)
+
+var predicatePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// field tagged `dgraph:"password"`, written as dgraph password type and never read back
+func isPasswordField(field reflect.StructField) bool {
+	return field.Tag.Get("dgraph") == "password"
+}
+
+// plaintext as password values of subject (uid or blank node), sorted by predicate
+// sent with the mutation saving the node
+func passwordNQuads(subject string, passwords map[string]string) ([]*api.NQuad, error) {
+	predicates := make([]string, 0, len(passwords))
+	for predicate := range passwords {
+		predicates = append(predicates, predicate)
+	}
+	sort.Strings(predicates)
+
+	sets := []*api.NQuad{}
+	for _, predicate := range predicates {
+		apiVal, err := parseAsApiValue(Password(passwords[predicate]))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to save password %s: %+v", predicate, err)
+		}
+		sets = append(sets, &api.NQuad{Subject: subject, Predicate: predicate, ObjectValue: apiVal})
+	}
+	return sets, nil
+}
+
+// clear password fields and their recorded changes once written,
+// so plaintext does not stay on model or binder
+func clearPasswords(binder *ModelBinder) {
+	ForEachField(binder.Model(), func(i int, field *reflect.Value, stField reflect.StructField) error {
+		if isPasswordField(stField) {
+			field.Set(reflect.Zero(field.Type()))
+			binder.ClearChange(stField.Name)
+		}
+		return nil
+	})
+}
+
+// compare plaintext with password predicate of uid using checkpwd
+func CheckPassword(tx *DGraphTxn, ctx context.Context, uid string, predicate string, plaintext string) (bool, error) {
+	if !predicatePattern.MatchString(predicate) {
+		return false, fmt.Errorf("Invalid predicate: %s", predicate)
+	}
+	q := fmt.Sprintf(`query check($uid: string, $password: string) {
+		check(func: uid($uid)) {
+			valid: checkpwd(%s, $password)
+		}
+	}`, predicate)
+	resp, err := tx.QueryWithVars(ctx, q, map[string]string{"$uid": uid, "$password": plaintext})
+	if err != nil {
+		return false, err
+	}
+
+	var result struct {
+		Check []struct {
+			Valid bool `json:"valid"`
+		} `json:"check"`
+	}
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return false, err
+	}
+	if len(result.Check) == 0 {
+		return false, ErrNotFound
+	}
+	return result.Check[0].Valid, nil
+}
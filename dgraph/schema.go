@@ -0,0 +1,109 @@
+package dgraph
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// schema (rdf) of predicates of model, sorted by predicate
+// index directives are only added where a type requires one (geo),
+// or for `dgraph:"upsert"` fields: @index(exact) @upsert, needed for SaveIfNotExist on that field
+func ModelSchema(model interface{}) (string, error) {
+	modelType := reflect.TypeOf(model)
+	for modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		return "", fmt.Errorf("Not a struct: %s", modelType.String())
+	}
+
+	lines := []string{}
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		predicate := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Name == "UID" || predicate == "" || predicate == "-" || field.PkgPath != "" {
+			continue
+		}
+		dqlType, err := predicateType(field)
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %+v", modelType.Name(), field.Name, err)
+		}
+		if isUpsertField(field) {
+			switch dqlType {
+			case "string":
+				dqlType += " @index(exact) @upsert"
+			case "int":
+				dqlType += " @index(int) @upsert"
+			default:
+				return "", fmt.Errorf("%s.%s: @upsert requires a string or int predicate", modelType.Name(), field.Name)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s .", predicate, dqlType))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+// unique field, checked by SaveIfNotExist, example: Email string `json:"email" dgraph:"upsert"`
+func isUpsertField(field reflect.StructField) bool {
+	return field.Tag.Get("dgraph") == "upsert"
+}
+
+func predicateType(field reflect.StructField) (string, error) {
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	switch {
+	case isPasswordField(field):
+		return "password", nil
+	case fieldType == reflect.TypeOf(Geo{}), fieldType == reflect.TypeOf(GeoWKB{}):
+		return "geo @index(geo)", nil
+	case IsLocalizedString(fieldType):
+		return "string @lang", nil
+	case FieldLanguage(field) != "":
+		return "string @lang", nil
+	}
+
+	if fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() != reflect.Uint8 {
+		elemType := fieldType.Elem()
+		if elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() == reflect.Struct && elemType.String() != "time.Time" {
+			return "[uid]", nil
+		}
+		scalar, err := scalarType(elemType)
+		if err != nil {
+			return "", err
+		}
+		return "[" + scalar + "]", nil
+	}
+	if fieldType.Kind() == reflect.Struct && fieldType.String() != "time.Time" {
+		return "uid", nil
+	}
+	return scalarType(fieldType)
+}
+
+func scalarType(fieldType reflect.Type) (string, error) {
+	if fieldType.String() == "time.Time" {
+		return "datetime", nil
+	}
+	switch fieldType.Kind() {
+	case reflect.String, reflect.Array:
+		return "string", nil
+	case reflect.Bool:
+		return "bool", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int", nil
+	case reflect.Float32, reflect.Float64:
+		return "float", nil
+	case reflect.Slice:
+		return "string", nil //[]byte
+	}
+	return "", fmt.Errorf("Unsupported predicate type %s", fieldType.String())
+}
@@ -15,8 +15,8 @@ import (
 
 	// "fmt"
 
-	"github.com/dgraph-io/dgo"
-	"github.com/dgraph-io/dgo/protos/api"
+	"github.com/dgraph-io/dgo/v2"
+	"github.com/dgraph-io/dgo/v2/protos/api"
 	"github.com/gobuffalo/plush"
 	"google.golang.org/grpc"
 
@@ -170,7 +170,7 @@ func TestTestSuite(t *testing.T) {
 		panic(err)
 	}
 
-	testS.Logger = *ctx.Value("log").(*lib.Logger)
+	testS.Logger = lib.LoggerFrom(ctx)
 	dbConn := ctx.Value("db").(*grpc.ClientConn)
 	testS.DBCon = dbConn
 	testS.DBClient = dgo.NewDgraphClient(api.NewDgraphClient(dbConn))
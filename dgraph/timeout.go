@@ -0,0 +1,59 @@
+package dgraph
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaults of Config timeouts, applied when context has no deadline
+var (
+	QueryTimeout  = 30 * time.Second
+	MutateTimeout = 30 * time.Second
+	CommitTimeout = 30 * time.Second
+	SchemaTimeout = 5 * time.Minute
+)
+
+// derive context with timeout unless ctx already has a deadline or timeout is 0
+// @return timeout applied, 0 when none
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc, time.Duration) {
+	if timeout <= 0 {
+		return ctx, func() {}, 0
+	}
+	if _, has := ctx.Deadline(); has {
+		return ctx, func() {}, 0
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, timeout
+}
+
+// wrap err as TimeoutError when the deadline of ctx was exceeded
+func timeoutError(ctx context.Context, op string, applied time.Duration, err error) error {
+	if err == nil {
+		return nil
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return err
+	}
+	if ctx.Err() == context.DeadlineExceeded || status.Code(err) == codes.DeadlineExceeded {
+		return &TimeoutError{Op: op, Timeout: applied, Err: err}
+	}
+	return err
+}
+
+// error of a cancelled or expired ctx between steps of a long operation, nil while ctx is live
+func contextError(ctx context.Context, op string) error {
+	select {
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return &TimeoutError{Op: op, Err: ctx.Err()}
+		}
+		return ctx.Err()
+	default:
+		return nil
+	}
+}
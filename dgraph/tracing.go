@@ -0,0 +1,14 @@
+package dgraph
+
+import (
+	"context"
+
+	"github.com/u007/gobinder/lib"
+)
+
+// run model lifecycle hook within span "binder.<name>"
+func traceHook(tx *DGraphTxn, ctx context.Context, name string, hook func(context.Context) error) (err error) {
+	ctx, span := tx.config(ctx).StartSpan(ctx, "binder."+name, lib.Attr("hook", name))
+	defer func() { lib.EndSpan(span, err) }()
+	return hook(ctx)
+}
@@ -0,0 +1,122 @@
+package dgraph
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/dgraph-io/dgo/v2"
+	"github.com/u007/gobinder/lib"
+)
+
+type TxnOptions struct {
+	MaxAttempts    int           // total runs of the function, default 3
+	InitialBackoff time.Duration // wait before 2nd attempt, doubled on each retry, default 50ms
+	MaxBackoff     time.Duration // default 2s
+	Jitter         float64       // 0..1, fraction of backoff randomized, default 0.2
+
+	Config *Config // config of each transaction, nil for context / package defaults
+}
+
+func DefaultTxnOptions() TxnOptions {
+	return TxnOptions{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Jitter:         0.2,
+	}
+}
+
+func (o TxnOptions) withDefaults() TxnOptions {
+	defaults := DefaultTxnOptions()
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = defaults.MaxAttempts
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = defaults.InitialBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = defaults.MaxBackoff
+	}
+	if o.Jitter < 0 || o.Jitter > 1 {
+		o.Jitter = defaults.Jitter
+	}
+	return o
+}
+
+// wait before attempt (2nd attempt onwards)
+func (o TxnOptions) backoff(attempt int) time.Duration {
+	wait := o.InitialBackoff
+	for i := 2; i < attempt && wait < o.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > o.MaxBackoff {
+		wait = o.MaxBackoff
+	}
+	if o.Jitter > 0 {
+		delta := float64(wait) * o.Jitter
+		wait = time.Duration(float64(wait) - delta + rand.Float64()*2*delta)
+	}
+	return wait
+}
+
+func NewDGraphTxnFromClient(client *dgo.Dgraph) *DGraphTxn {
+	return &DGraphTxn{Client: client, Tx: client.NewTxn()}
+}
+
+func IsAborted(err error) bool {
+	return errors.Is(err, dgo.ErrAborted)
+}
+
+// run fn in a new transaction and commit it
+// when aborted (conflict), the transaction is discarded and fn rerun in a fresh transaction,
+// with exponential backoff and jitter, up to MaxAttempts
+// fn must not commit, and should be safe to rerun
+func RunInTxn(ctx context.Context, client *dgo.Dgraph, fn func(tx *DGraphTxn) error, opts ...TxnOptions) (err error) {
+	options := DefaultTxnOptions()
+	if len(opts) > 0 {
+		options = opts[0].withDefaults()
+	}
+	config := (&DGraphTxn{Config: options.Config}).config(ctx)
+
+	attempt := 0
+	ctx, span := config.StartSpan(ctx, "dgraph.RunInTxn")
+	defer func() {
+		span.SetAttributes(lib.Attr("attempts", attempt), lib.Attr("retry", attempt-1))
+		lib.EndSpan(span, err)
+	}()
+
+	for {
+		attempt++
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		tx := NewDGraphTxnFromClient(client)
+		tx.Config = options.Config
+		err = fn(tx)
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		if err == nil {
+			return nil
+		}
+		//no-op when already committed or aborted
+		tx.Discard(ctx)
+
+		if !IsAborted(err) || attempt >= options.MaxAttempts {
+			return err
+		}
+
+		config.MetricsRecorder().IncRetry()
+		logging(ctx).Debugf("transaction aborted, retrying attempt %d of %d", attempt+1, options.MaxAttempts)
+		timer := time.NewTimer(options.backoff(attempt + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package dgraph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v2"
+	"github.com/dgraph-io/dgo/v2/protos/api"
+	"google.golang.org/grpc"
+)
+
+// read-only transaction, mutations fail with ErrReadOnlyTxn
+func NewReadOnlyDGraphTxn(connection *grpc.ClientConn) *DGraphTxn {
+	return NewReadOnlyDGraphTxnFromClient(dgo.NewDgraphClient(api.NewDgraphClient(connection)))
+}
+
+func NewReadOnlyDGraphTxnFromClient(client *dgo.Dgraph) *DGraphTxn {
+	return &DGraphTxn{Client: client, Tx: client.NewReadOnlyTxn(), ReadOnly: true}
+}
+
+// read-only transaction which may read slightly stale data, served without contacting zero
+func NewBestEffortDGraphTxn(connection *grpc.ClientConn) *DGraphTxn {
+	return NewBestEffortDGraphTxnFromClient(dgo.NewDgraphClient(api.NewDgraphClient(connection)))
+}
+
+func NewBestEffortDGraphTxnFromClient(client *dgo.Dgraph) *DGraphTxn {
+	return &DGraphTxn{Client: client, Tx: client.NewReadOnlyTxn().BestEffort(), ReadOnly: true, BestEffort: true}
+}
+
+func (d *DGraphTxn) checkWritable(action string) error {
+	if d.ReadOnly {
+		return fmt.Errorf("%s: %w", action, ErrReadOnlyTxn)
+	}
+	return nil
+}
+
+// transaction for a graphql operation type: read-only for query and subscription, read-write for mutation
+func NewDGraphTxnForOperation(client *dgo.Dgraph, operation string, bestEffort bool) (*DGraphTxn, error) {
+	switch operation {
+	case "query", "subscription":
+		if bestEffort {
+			return NewBestEffortDGraphTxnFromClient(client), nil
+		}
+		return NewReadOnlyDGraphTxnFromClient(client), nil
+	case "mutation":
+		return NewDGraphTxnFromClient(client), nil
+	}
+	return nil, fmt.Errorf("Unknown graphql operation: %s", operation)
+}
+
+// transaction for a graphql request, see GraphQLOperationType
+func NewDGraphTxnForGraphQL(client *dgo.Dgraph, query string, operationName string, bestEffort bool) (*DGraphTxn, error) {
+	operation, err := GraphQLOperationType(query, operationName)
+	if err != nil {
+		return nil, err
+	}
+	return NewDGraphTxnForOperation(client, operation, bestEffort)
+}
+
+// query, mutation or subscription of the operation executed by a graphql request document
+// @param operationName optional when document has only 1 operation
+func GraphQLOperationType(query string, operationName string) (string, error) {
+	type operation struct {
+		kind string
+		name string
+	}
+	operations := []operation{}
+	var pending *operation
+	braces, parens := 0, 0
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '"':
+			if strings.HasPrefix(query[i:], `"""`) {
+				end := strings.Index(query[i+3:], `"""`)
+				if end < 0 {
+					return "", fmt.Errorf("Unterminated block string")
+				}
+				i += end + 6
+				continue
+			}
+			i++
+			for i < len(query) && query[i] != '"' {
+				if query[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			continue
+		case c == '(':
+			parens++
+		case c == ')':
+			parens--
+		case c == '{' && parens == 0:
+			if braces == 0 {
+				if pending == nil {
+					operations = append(operations, operation{kind: "query"})
+				} else if pending.kind != "fragment" {
+					operations = append(operations, *pending)
+				}
+				pending = nil
+			}
+			braces++
+		case c == '}' && parens == 0:
+			braces--
+		case braces == 0 && parens == 0 && isNameStart(c):
+			start := i
+			for i < len(query) && isNameChar(query[i]) {
+				i++
+			}
+			word := query[start:i]
+			if pending == nil {
+				switch word {
+				case "query", "mutation", "subscription", "fragment":
+					pending = &operation{kind: word}
+				}
+			} else if pending.name == "" {
+				pending.name = word
+			}
+			continue
+		}
+		i++
+	}
+
+	if len(operations) == 0 {
+		return "", fmt.Errorf("No graphql operation found")
+	}
+	if operationName == "" {
+		if len(operations) > 1 {
+			return "", fmt.Errorf("Operation name required for document with %d operations", len(operations))
+		}
+		return operations[0].kind, nil
+	}
+	for _, op := range operations {
+		if op.name == operationName {
+			return op.kind, nil
+		}
+	}
+	return "", fmt.Errorf("Unknown operation name: %s", operationName)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
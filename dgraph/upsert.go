@@ -0,0 +1,100 @@
+package dgraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v2/protos/api"
+	"github.com/gobuffalo/validate"
+)
+
+// run query and conditional mutations as a single atomic request
+// mutations may use @if(...) in Cond and uid(v) of query variables
+// example:
+//
+//	tx.Upsert(ctx, `query q($email: string) { q(func: eq(email, $email)) { v as uid } }`,
+//		map[string]string{"$email": email},
+//		&api.Mutation{Cond: "@if(eq(len(v), 0))", SetJson: data})
+func (d *DGraphTxn) Upsert(ctx context.Context, query string, vars map[string]string, mutations ...*api.Mutation) (*api.Response, error) {
+	if len(mutations) == 0 {
+		return nil, fmt.Errorf("Upsert requires at least 1 mutation")
+	}
+	return d.Do(ctx, &api.Request{Query: query, Vars: vars, Mutations: mutations})
+}
+
+// create binder only if no node of its table matches where, in a single upsert
+// concurrent creates only conflict (and one aborts) when the predicates of where are
+// indexed with @upsert, see the dgraph:"upsert" tag of ModelSchema
+// @return created false when a matching node exists (nothing is written)
+func saveBinderIfAbsent(tx *DGraphTxn, ctx context.Context, binder *ModelBinder, where string, whereValues map[string]QVar) (bool, *validate.Errors, error) {
+	if err := tx.checkWritable("save binder"); err != nil {
+		return false, validate.NewErrors(), err
+	}
+	plan, verrs, err := prepareSave(tx, ctx, binder)
+	if err != nil {
+		return false, verrs, err
+	}
+	if plan.action != "create" {
+		plan.rollback(binder)
+		return false, verrs, fmt.Errorf("Upsert create requires a new model")
+	}
+
+	query, vars := existsQuery(plan.hash["_type"].(string), where, whereValues)
+	mu, err := plan.mutation("new")
+	if err != nil {
+		plan.rollback(binder)
+		return false, verrs, err
+	}
+	mu.Cond = "@if(eq(len(v), 0))"
+	resp, err := tx.Upsert(ctx, query, vars, mu)
+	if err != nil {
+		plan.rollback(binder)
+		return false, verrs, err
+	}
+	uid, created := resp.Uids["new"]
+	if !created {
+		plan.rollback(binder)
+		return false, verrs, nil
+	}
+	if err := applySave(binder, plan, uid); err != nil {
+		return false, verrs, err
+	}
+	return true, verrs, completeSave(tx, ctx, binder, plan)
+}
+
+// query binding v to nodes of table matching where
+// rooted on the indexed _type predicate so only nodes of table are scanned
+func existsQuery(table string, where string, whereValues map[string]QVar) (string, map[string]string) {
+	names := make([]string, 0, len(whereValues))
+	for name := range whereValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	decls := []string{}
+	vars := map[string]string{}
+	for _, name := range names {
+		qvar := whereValues[name]
+		varName := "$" + strings.TrimPrefix(name, "$")
+		decls = append(decls, fmt.Sprintf("%s: %s", varName, qvar.Type))
+		vars[varName] = fmt.Sprint(qvar.Value)
+	}
+
+	header := "query exists"
+	if len(decls) > 0 {
+		header = fmt.Sprintf("query exists(%s)", strings.Join(decls, ", "))
+	}
+	filter := ""
+	if where != "" {
+		filter = fmt.Sprintf(" @filter(%s)", where)
+	}
+	query := fmt.Sprintf(`%s {
+		exists(func: eq(_type, %q))%s {
+			v as uid
+		}
+	}`, header, table, filter)
+	return query, vars
+}
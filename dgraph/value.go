@@ -0,0 +1,105 @@
+package dgraph
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/dgraph-io/dgo/v2/protos/api"
+)
+
+// custom value types written by MutateField, example: geo types
+type ApiValuer interface {
+	ApiValue() (*api.Value, error)
+}
+
+// written as dgraph password type
+type Password string
+
+// geometry in well known binary, written as dgraph geo type
+type GeoWKB []byte
+
+func parseAsApiValue(value interface{}) (*api.Value, error) {
+	if value == nil {
+		return &api.Value{}, fmt.Errorf("Unsupported nil value")
+	}
+	if valuer, ok := value.(ApiValuer); ok {
+		return valuer.ApiValue()
+	}
+
+	switch v := value.(type) {
+	case Password:
+		return &api.Value{Val: &api.Value_PasswordVal{string(v)}}, nil
+	case GeoWKB:
+		return &api.Value{Val: &api.Value_GeoVal{[]byte(v)}}, nil
+	case Geo:
+		return geoApiValue(v)
+	case time.Time:
+		return datetimeValue(v)
+	case []byte:
+		return &api.Value{Val: &api.Value_BytesVal{v}}, nil
+	}
+
+	val := reflect.ValueOf(value)
+	//get value instead of ptr
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return &api.Value{}, fmt.Errorf("Unsupported nil %s", val.Type().String())
+		}
+		return parseAsApiValue(val.Elem().Interface())
+	}
+
+	//uuid.UUID and other fixed size ids
+	if stringer, ok := value.(fmt.Stringer); ok && val.Kind() == reflect.Array {
+		return &api.Value{Val: &api.Value_DefaultVal{stringer.String()}}, nil
+	}
+
+	switch val.Kind() {
+	case reflect.String:
+		return &api.Value{Val: &api.Value_DefaultVal{val.String()}}, nil
+	case reflect.Bool:
+		return &api.Value{Val: &api.Value_BoolVal{val.Bool()}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &api.Value{Val: &api.Value_IntVal{val.Int()}}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val.Uint() > math.MaxInt64 {
+			return &api.Value{}, fmt.Errorf("Value overflows int64: %d", val.Uint())
+		}
+		return &api.Value{Val: &api.Value_IntVal{int64(val.Uint())}}, nil
+	case reflect.Float32, reflect.Float64:
+		return &api.Value{Val: &api.Value_DoubleVal{val.Float()}}, nil
+	case reflect.Slice:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return &api.Value{Val: &api.Value_BytesVal{val.Bytes()}}, nil
+		}
+		return &api.Value{}, fmt.Errorf("Unsupported slice %s, use MutateField for lists", val.Type().String())
+	}
+	return &api.Value{}, fmt.Errorf("Unsupported type %s", val.Type().String())
+}
+
+func datetimeValue(t time.Time) (*api.Value, error) {
+	data, err := t.MarshalBinary()
+	if err != nil {
+		return &api.Value{}, err
+	}
+	return &api.Value{Val: &api.Value_DatetimeVal{data}}, nil
+}
+
+// slice written as list predicate, []byte and GeoWKB are single values
+func isListValue(value interface{}) bool {
+	if _, ok := value.(ApiValuer); ok {
+		return false
+	}
+	val := reflect.Indirect(reflect.ValueOf(value))
+	return val.IsValid() && val.Kind() == reflect.Slice && val.Type().Elem().Kind() != reflect.Uint8
+}
+
+func listValues(value interface{}) []interface{} {
+	val := reflect.Indirect(reflect.ValueOf(value))
+	values := make([]interface{}, val.Len())
+	for i := range values {
+		values[i] = val.Index(i).Interface()
+	}
+	return values
+}
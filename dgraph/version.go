@@ -0,0 +1,76 @@
+package dgraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/dgraph-io/dgo/v2/protos/api"
+	"golang.org/x/net/context"
+)
+
+// field tagged `binder:"version"` (int) used for optimistic locking
+func versionField(model interface{}) (reflect.StructField, bool) {
+	field, err := FieldByTagName(model, "binder", "version")
+	if err != nil {
+		return field, false
+	}
+	switch field.Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field, true
+	}
+	return field, false
+}
+
+// version as loaded from database, before any binder.Set on it
+func loadedVersion(binder *ModelBinder, field reflect.StructField) int64 {
+	if binder.Changed(field.Name) {
+		return reflect.ValueOf(binder.OldValue(field.Name)).Int()
+	}
+	return reflect.ValueOf(binder.Get(field.Name)).Int()
+}
+
+func setVersion(model interface{}, field reflect.StructField, version int64) {
+	reflect.Indirect(reflect.ValueOf(model)).FieldByName(field.Name).SetInt(version)
+}
+
+// apply mutation only when stored version still matches the loaded version
+// @return ErrStaleObject on mismatch
+func saveVersioned(tx *DGraphTxn, ctx context.Context, id string, mu *api.Mutation, predicate string, loaded int64) error {
+	query := fmt.Sprintf(`query version($id: string) {
+		version(func: uid($id)) @filter(%s) {
+			v as uid
+		}
+	}`, versionFilter(predicate, loaded))
+
+	mu.Cond = "@if(eq(len(v), 1))"
+	resp, err := tx.Do(ctx, &api.Request{
+		Query:     query,
+		Vars:      map[string]string{"$id": id},
+		Mutations: []*api.Mutation{mu},
+	})
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Version []struct {
+			UID string `json:"uid"`
+		} `json:"version"`
+	}
+	if err := json.Unmarshal(resp.Json, &result); err != nil {
+		return err
+	}
+	if len(result.Version) == 0 {
+		return fmt.Errorf("%s version %d: %w", id, loaded, ErrStaleObject)
+	}
+	return nil
+}
+
+// filter matching a node still at loaded version, nodes saved before versioning count as 0
+func versionFilter(predicate string, loaded int64) string {
+	if loaded == 0 {
+		return fmt.Sprintf("(eq(%s, 0) OR NOT has(%s))", predicate, predicate)
+	}
+	return fmt.Sprintf("eq(%s, %d)", predicate, loaded)
+}
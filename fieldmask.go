@@ -0,0 +1,186 @@
+package gobinder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// apply payload only to paths of an update mask (google.protobuf.FieldMask style)
+// paths are json names, nested relation struct separated by "." example: single_role.name
+// a path missing from payload resets the field to its zero value
+// rejects the whole mask if any path is not in the model
+func (this *ModelBinder) ApplyFieldMask(payload map[string]interface{}, paths []string, markChanged bool) error {
+	modelType := reflect.TypeOf(this.Model()).Elem()
+
+	nested := map[string][][]string{}
+	topLevel := []string{}
+	for _, path := range paths {
+		parts := strings.Split(path, ".")
+		if err := validateMaskPath(modelType, parts); err != nil {
+			return fmt.Errorf("Invalid field mask path %s: %+v", path, err)
+		}
+		if len(parts) == 1 {
+			topLevel = append(topLevel, parts[0])
+			continue
+		}
+		nested[parts[0]] = append(nested[parts[0]], parts[1:])
+	}
+
+	for _, name := range topLevel {
+		field, _ := fieldByJsonName(modelType, name)
+		if err := this.Set(field.Name, payload[name], markChanged); err != nil {
+			return fmt.Errorf("Error setting %s, error: %+v", name, err)
+		}
+		//whole relation replaced, nested paths are covered
+		delete(nested, name)
+	}
+
+	for name, subPaths := range nested {
+		field, _ := fieldByJsonName(modelType, name)
+		subPayload, _ := payload[name].(map[string]interface{})
+
+		//copy of current relation so change is recorded on the relation field
+		structType := field.Type
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+		relation := reflect.New(structType)
+		current := reflect.Indirect(reflect.ValueOf(this.Get(field.Name)))
+		if current.IsValid() {
+			relation.Elem().Set(current)
+		}
+
+		if err := applyMaskPaths(this, relation.Elem(), subPayload, subPaths); err != nil {
+			return fmt.Errorf("Error setting %s, error: %+v", name, err)
+		}
+		if err := this.SetValue(field.Name, relation, markChanged); err != nil {
+			return fmt.Errorf("Error setting %s, error: %+v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// update mask of changed fields, nested paths for changed relation struct
+func (this *ModelBinder) FieldMask() []string {
+	modelType := reflect.TypeOf(this.Model()).Elem()
+	paths := []string{}
+	for name, change := range this.ChangesSnapshot() {
+		field, ok := modelType.FieldByName(name)
+		if !ok {
+			continue
+		}
+		jsonName := jsonTagName(field)
+
+		oldVal := reflect.Indirect(reflect.ValueOf(change[0]))
+		newVal := reflect.Indirect(reflect.ValueOf(change[1]))
+		if oldVal.IsValid() && newVal.IsValid() && isMaskStruct(oldVal.Type()) {
+			paths = append(paths, structMaskPaths(jsonName, oldVal, newVal)...)
+			continue
+		}
+		paths = append(paths, jsonName)
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+func structMaskPaths(prefix string, oldVal reflect.Value, newVal reflect.Value) []string {
+	paths := []string{}
+	for i := 0; i < oldVal.NumField(); i++ {
+		field := oldVal.Type().Field(i)
+		jsonName := jsonTagName(field)
+		if jsonName == "" || jsonName == "-" || field.PkgPath != "" {
+			continue
+		}
+		oldField := reflect.Indirect(oldVal.Field(i))
+		newField := reflect.Indirect(newVal.Field(i))
+		if oldField.IsValid() && newField.IsValid() && isMaskStruct(oldField.Type()) {
+			paths = append(paths, structMaskPaths(prefix+"."+jsonName, oldField, newField)...)
+			continue
+		}
+		if !reflect.DeepEqual(oldVal.Field(i).Interface(), newVal.Field(i).Interface()) {
+			paths = append(paths, prefix+"."+jsonName)
+		}
+	}
+	return paths
+}
+
+func applyMaskPaths(binder *ModelBinder, target reflect.Value, payload map[string]interface{}, paths [][]string) error {
+	nested := map[string][][]string{}
+	for _, parts := range paths {
+		field, _ := fieldByJsonName(target.Type(), parts[0])
+		fieldValue := target.FieldByName(field.Name)
+		if len(parts) > 1 {
+			nested[parts[0]] = append(nested[parts[0]], parts[1:])
+			continue
+		}
+		if err := BindFieldValue(binder.Context, parts[0], &fieldValue, reflect.ValueOf(payload[parts[0]])); err != nil {
+			return err
+		}
+	}
+
+	for name, subPaths := range nested {
+		field, _ := fieldByJsonName(target.Type(), name)
+		fieldValue := target.FieldByName(field.Name)
+		if fieldValue.Kind() == reflect.Ptr {
+			//copy pointed struct, target is a copy that must not share it with the model
+			relation := reflect.New(fieldValue.Type().Elem())
+			if !fieldValue.IsNil() {
+				relation.Elem().Set(fieldValue.Elem())
+			}
+			fieldValue.Set(relation)
+			fieldValue = fieldValue.Elem()
+		}
+		subPayload, _ := payload[name].(map[string]interface{})
+		if err := applyMaskPaths(binder, fieldValue, subPayload, subPaths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateMaskPath(structType reflect.Type, parts []string) error {
+	for i, part := range parts {
+		field, ok := fieldByJsonName(structType, part)
+		if !ok {
+			return fmt.Errorf("%s not found in %s", part, structType.Name())
+		}
+		if i == len(parts)-1 {
+			return nil
+		}
+
+		structType = field.Type
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+		if !isMaskStruct(structType) {
+			return fmt.Errorf("%s is not a struct relation", part)
+		}
+	}
+	return nil
+}
+
+func fieldByJsonName(structType reflect.Type, name string) (reflect.StructField, bool) {
+	if name == "" || name == "-" {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if jsonTagName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// json name of field, without options such as omitempty
+func jsonTagName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+func isMaskStruct(valType reflect.Type) bool {
+	return valType.Kind() == reflect.Struct && valType.String() != "time.Time"
+}
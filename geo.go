@@ -0,0 +1,133 @@
+package gobinder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	GeoPoint   = "Point"
+	GeoPolygon = "Polygon"
+)
+
+// GeoJSON point or polygon, coordinates are [lng, lat]
+// Coordinates is []float64 for Point, [][][]float64 (rings) for Polygon
+type Geo struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+func NewPoint(lat float64, lng float64) Geo {
+	return Geo{Type: GeoPoint, Coordinates: []float64{lng, lat}}
+}
+
+// @param rings outer ring first, each ring a list of [lng, lat], closed (first == last)
+func NewPolygon(rings ...[][]float64) Geo {
+	return Geo{Type: GeoPolygon, Coordinates: rings}
+}
+
+// geo from GeoJSON map, GeoJSON string, "lat,lng" string or Geo
+func ParseGeo(value interface{}) (Geo, error) {
+	switch v := value.(type) {
+	case Geo:
+		return v, nil
+	case *Geo:
+		return *v, nil
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(v), "{") {
+			var geo Geo
+			err := json.Unmarshal([]byte(v), &geo)
+			return geo, err
+		}
+		parts := strings.Split(v, ",")
+		if len(parts) != 2 {
+			return Geo{}, fmt.Errorf("Invalid lat,lng: %s", v)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil {
+			return Geo{}, fmt.Errorf("Invalid latitude: %s", parts[0])
+		}
+		lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return Geo{}, fmt.Errorf("Invalid longitude: %s", parts[1])
+		}
+		return NewPoint(lat, lng), nil
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return Geo{}, err
+		}
+		var geo Geo
+		err = json.Unmarshal(data, &geo)
+		return geo, err
+	}
+	return Geo{}, fmt.Errorf("Unsupported geo value: %T", value)
+}
+
+// decode GeoJSON, coordinates typed by geometry type
+func (g *Geo) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	switch raw.Type {
+	case GeoPoint:
+		point := []float64{}
+		if err := json.Unmarshal(raw.Coordinates, &point); err != nil {
+			return err
+		}
+		if len(point) != 2 {
+			return fmt.Errorf("Point requires [lng, lat]")
+		}
+		g.Coordinates = point
+	case GeoPolygon:
+		rings := [][][]float64{}
+		if err := json.Unmarshal(raw.Coordinates, &rings); err != nil {
+			return err
+		}
+		g.Coordinates = rings
+	default:
+		return fmt.Errorf("Unsupported geo type: %s", raw.Type)
+	}
+	g.Type = raw.Type
+	return nil
+}
+
+func (g Geo) IsZero() bool {
+	return g.Type == ""
+}
+
+// latitude and longitude of a Point
+func (g Geo) LatLng() (float64, float64, error) {
+	point, ok := g.Coordinates.([]float64)
+	if g.Type != GeoPoint || !ok || len(point) != 2 {
+		return 0, 0, fmt.Errorf("Not a point: %s", g.Type)
+	}
+	return point[1], point[0], nil
+}
+
+// polygon rings
+func (g Geo) Rings() ([][][]float64, error) {
+	if g.Type != GeoPolygon {
+		return nil, fmt.Errorf("Not a polygon: %s", g.Type)
+	}
+	if rings, ok := g.Coordinates.([][][]float64); ok {
+		return rings, nil
+	}
+	return nil, fmt.Errorf("Invalid polygon coordinates: %T", g.Coordinates)
+}
+
+// coordinates in dql form, example: [101.6, 3.1]
+func (g Geo) DQL() (string, error) {
+	data, err := json.Marshal(g.Coordinates)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,86 @@
+package gobinder
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// string per language, written as predicate@lang, key "" is the untagged predicate
+type LocalizedString map[string]string
+
+type langContextKey int
+
+const languagesKey langContextKey = iota
+
+// preferred languages of request, first is preferred, example: WithLanguages(ctx, "ms", "en")
+func WithLanguages(ctx context.Context, languages ...string) context.Context {
+	return context.WithValue(ctx, languagesKey, languages)
+}
+
+func LanguagesFrom(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	languages, _ := ctx.Value(languagesKey).([]string)
+	return languages
+}
+
+// value of first language found, then untagged value, then any language (sorted)
+func (l LocalizedString) Get(languages ...string) string {
+	for _, lang := range languages {
+		if value, ok := l[lang]; ok && value != "" {
+			return value
+		}
+	}
+	if value, ok := l[""]; ok {
+		return value
+	}
+	keys := l.Languages()
+	if len(keys) == 0 {
+		return ""
+	}
+	return l[keys[0]]
+}
+
+// value in languages of context, see WithLanguages
+func (l LocalizedString) In(ctx context.Context) string {
+	return l.Get(LanguagesFrom(ctx)...)
+}
+
+// languages with a value, sorted, untagged excluded
+func (l LocalizedString) Languages() []string {
+	keys := []string{}
+	for key := range l {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// language of field tagged `lang:"en"`, empty if not tagged
+func FieldLanguage(field reflect.StructField) string {
+	return field.Tag.Get("lang")
+}
+
+func IsLocalizedString(fieldType reflect.Type) bool {
+	return fieldType == reflect.TypeOf(LocalizedString{})
+}
+
+// accepts untagged string (stored as "") or object of language to string
+func (l *LocalizedString) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*l = LocalizedString{"": value}
+		return nil
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*l = LocalizedString(values)
+	return nil
+}
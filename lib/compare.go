@@ -6,6 +6,9 @@ import (
 )
 
 func IsEqualValue(ctx context.Context, valueA interface{}, valueB interface{}) (bool, error) {
+	if valueA == nil || valueB == nil {
+		return valueA == nil && valueB == nil, nil
+	}
 	typeA := reflect.TypeOf(valueA)
 
 	//slice compare
@@ -79,5 +82,32 @@ func IsEqualValue(ctx context.Context, valueA interface{}, valueB interface{}) (
 		return true, nil
 	}
 
+	//map compare, maps are not comparable with ==
+	if typeA.Kind() == reflect.Map {
+		valA := reflect.ValueOf(valueA)
+		valB := reflect.ValueOf(valueB)
+
+		if valB.Kind() != reflect.Map || valA.Type() != valB.Type() || valA.Len() != valB.Len() {
+			return false, nil
+		}
+		if valA.IsNil() != valB.IsNil() {
+			return false, nil
+		}
+
+		for _, key := range valA.MapKeys() {
+			indexValB := valB.MapIndex(key)
+			if !indexValB.IsValid() {
+				return false, nil
+			}
+			if is, err := IsEqualValue(ctx, valA.MapIndex(key).Interface(), indexValB.Interface()); err != nil {
+				return is, err
+			} else if !is {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	}
+
 	return valueA == valueB, nil
 }
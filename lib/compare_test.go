@@ -0,0 +1,43 @@
+package lib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type localized map[string]string
+
+func TestIsEqualValueMap(t *testing.T) {
+	ctx := context.Background()
+
+	equal, err := IsEqualValue(ctx, localized{"en": "cake", "ms": "kek"}, localized{"ms": "kek", "en": "cake"})
+	assert.NoError(t, err)
+	assert.True(t, equal)
+
+	equal, err = IsEqualValue(ctx, localized{"en": "cake"}, localized{"en": "pie"})
+	assert.NoError(t, err)
+	assert.False(t, equal)
+
+	equal, err = IsEqualValue(ctx, localized{"en": "cake"}, localized{"en": "cake", "ms": "kek"})
+	assert.NoError(t, err)
+	assert.False(t, equal)
+
+	equal, err = IsEqualValue(ctx, localized{"en": "cake"}, localized{"ms": "cake"})
+	assert.NoError(t, err)
+	assert.False(t, equal)
+
+	equal, err = IsEqualValue(ctx, localized(nil), localized{})
+	assert.NoError(t, err)
+	assert.False(t, equal)
+
+	equal, err = IsEqualValue(ctx, map[string]interface{}{"tags": localized{"en": "a"}, "none": nil},
+		map[string]interface{}{"tags": localized{"en": "a"}, "none": nil})
+	assert.NoError(t, err)
+	assert.True(t, equal)
+
+	equal, err = IsEqualValue(ctx, localized{"en": "cake"}, nil)
+	assert.NoError(t, err)
+	assert.False(t, equal)
+}
@@ -11,6 +11,55 @@ type Logger interface {
 	Errorf(string, ...interface{})
 }
 
+type contextKey int
+
+const loggerKey contextKey = iota
+
+// deprecated untyped key, still read for compatibility
+const legacyLoggerKey = "log"
+
+func WithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// logger from context, falls back to "log" key (Logger or *Logger), then a no-op logger
+func LoggerFrom(ctx context.Context) Logger {
+	if ctx == nil {
+		return NopLogger
+	}
+	if logger, ok := ctx.Value(loggerKey).(Logger); ok && logger != nil {
+		return logger
+	}
+
+	switch logger := ctx.Value(legacyLoggerKey).(type) {
+	case *Logger:
+		if logger != nil && *logger != nil {
+			return *logger
+		}
+	case Logger:
+		if logger != nil {
+			return logger
+		}
+	}
+	return NopLogger
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debugw(string, ...interface{})                        {}
+func (nopLogger) Infow(string, ...interface{})                         {}
+func (nopLogger) Warnw(string, ...interface{})                         {}
+func (nopLogger) Errorw(string, ...interface{})                        {}
+func (n nopLogger) With(keysAndValues ...interface{}) StructuredLogger { return n }
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+
+// discard all logs
+var NopLogger Logger = nopLogger{}
+
 func logging(ctx context.Context) Logger {
-	return *ctx.Value("log").(*Logger)
+	return LoggerFrom(ctx)
 }
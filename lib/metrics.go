@@ -0,0 +1,32 @@
+package lib
+
+import (
+	"time"
+)
+
+// operational metrics of dgraph transactions and binding, see metrics package for prometheus
+type Metrics interface {
+	// op: mutate, query, commit, do
+	ObserveOperation(op string, duration time.Duration, err error)
+	// server side latency reported by dgraph (api.Latency)
+	ObserveServerLatency(op string, parsing time.Duration, processing time.Duration, encoding time.Duration)
+	// transaction aborted (dgo.ErrAborted)
+	IncAbort(op string)
+	// transaction retried after abort
+	IncRetry()
+	// BinderValidate returned validation errors
+	IncValidationFailure(model string)
+	// BindFieldValue failed
+	IncBindError(field string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveOperation(string, time.Duration, error)                            {}
+func (nopMetrics) ObserveServerLatency(string, time.Duration, time.Duration, time.Duration) {}
+func (nopMetrics) IncAbort(string)                                                          {}
+func (nopMetrics) IncRetry()                                                                {}
+func (nopMetrics) IncValidationFailure(string)                                              {}
+func (nopMetrics) IncBindError(string)                                                      {}
+
+var NopMetrics Metrics = nopMetrics{}
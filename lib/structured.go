@@ -0,0 +1,148 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// logger with key/value fields, example: Debugw("saving", "model", "users", "uid", "0x1")
+type StructuredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+	With(keysAndValues ...interface{}) StructuredLogger
+}
+
+const structuredLoggerKey contextKey = loggerKey + 1
+
+func WithStructuredLogger(ctx context.Context, logger StructuredLogger) context.Context {
+	return context.WithValue(ctx, structuredLoggerKey, logger)
+}
+
+// structured logger from context, falls back to LoggerFrom wrapped with fields appended to message
+func StructuredLoggerFrom(ctx context.Context) StructuredLogger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(structuredLoggerKey).(StructuredLogger); ok && logger != nil {
+			return logger
+		}
+	}
+
+	logger := LoggerFrom(ctx)
+	if structured, ok := logger.(StructuredLogger); ok {
+		return structured
+	}
+	return NewPrintfStructuredLogger(logger)
+}
+
+// structured logger on top of printf style Logger, fields are written as key=value after message
+type printfLogger struct {
+	logger Logger
+	fields []interface{}
+}
+
+func NewPrintfStructuredLogger(logger Logger) StructuredLogger {
+	return &printfLogger{logger: logger}
+}
+
+func (l *printfLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.logger.Debugf("%s", l.format(msg, keysAndValues))
+}
+
+func (l *printfLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.logger.Infof("%s", l.format(msg, keysAndValues))
+}
+
+func (l *printfLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.logger.Warnf("%s", l.format(msg, keysAndValues))
+}
+
+func (l *printfLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.logger.Errorf("%s", l.format(msg, keysAndValues))
+}
+
+func (l *printfLogger) With(keysAndValues ...interface{}) StructuredLogger {
+	fields := append(l.fields[:len(l.fields):len(l.fields)], keysAndValues...)
+	return &printfLogger{logger: l.logger, fields: fields}
+}
+
+func (l *printfLogger) Debugf(format string, args ...interface{}) {
+	l.logger.Debugf("%s", l.format(fmt.Sprintf(format, args...), nil))
+}
+
+func (l *printfLogger) Infof(format string, args ...interface{}) {
+	l.logger.Infof("%s", l.format(fmt.Sprintf(format, args...), nil))
+}
+
+func (l *printfLogger) Warnf(format string, args ...interface{}) {
+	l.logger.Warnf("%s", l.format(fmt.Sprintf(format, args...), nil))
+}
+
+func (l *printfLogger) Errorf(format string, args ...interface{}) {
+	l.logger.Errorf("%s", l.format(fmt.Sprintf(format, args...), nil))
+}
+
+func (l *printfLogger) format(msg string, keysAndValues []interface{}) string {
+	fields := append(l.fields[:len(l.fields):len(l.fields)], keysAndValues...)
+	if len(fields) == 0 {
+		return msg
+	}
+
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(fields); i += 2 {
+		if i+1 < len(fields) {
+			fmt.Fprintf(&b, " %v=%+v", fields[i], fields[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v=(MISSING)", fields[i])
+		}
+	}
+	return b.String()
+}
+
+// structured logger on top of log/slog
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func NewSlogLogger(logger *slog.Logger) StructuredLogger {
+	return &slogLogger{logger: logger}
+}
+
+func (l *slogLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.logger.Debug(msg, keysAndValues...)
+}
+
+func (l *slogLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.logger.Info(msg, keysAndValues...)
+}
+
+func (l *slogLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.logger.Warn(msg, keysAndValues...)
+}
+
+func (l *slogLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.logger.Error(msg, keysAndValues...)
+}
+
+func (l *slogLogger) With(keysAndValues ...interface{}) StructuredLogger {
+	return &slogLogger{logger: l.logger.With(keysAndValues...)}
+}
+
+func (l *slogLogger) Debugf(format string, args ...interface{}) {
+	l.logger.Debug(fmt.Sprintf(format, args...))
+}
+
+func (l *slogLogger) Infof(format string, args ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, args...))
+}
+
+func (l *slogLogger) Warnf(format string, args ...interface{}) {
+	l.logger.Warn(fmt.Sprintf(format, args...))
+}
+
+func (l *slogLogger) Errorf(format string, args ...interface{}) {
+	l.logger.Error(fmt.Sprintf(format, args...))
+}
@@ -6,6 +6,22 @@ import (
 	"github.com/u007/gobinder/lib"
 )
 
+func WithLogger(ctx context.Context, logger lib.Logger) context.Context {
+	return lib.WithLogger(ctx, logger)
+}
+
+func LoggerFrom(ctx context.Context) lib.Logger {
+	return lib.LoggerFrom(ctx)
+}
+
 func logging(ctx context.Context) lib.Logger {
-	return *ctx.Value("log").(*lib.Logger)
+	return lib.LoggerFrom(ctx)
+}
+
+func WithStructuredLogger(ctx context.Context, logger lib.StructuredLogger) context.Context {
+	return lib.WithStructuredLogger(ctx, logger)
+}
+
+func structuredLogging(ctx context.Context) lib.StructuredLogger {
+	return lib.StructuredLoggerFrom(ctx)
 }
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// prometheus collector implementing lib.Metrics
+// usage:
+//
+//	collector := metrics.NewCollector("myapp")
+//	prometheus.MustRegister(collector)
+//	config.Metrics = collector
+type Collector struct {
+	operations         *prometheus.CounterVec
+	duration           *prometheus.HistogramVec
+	serverLatency      *prometheus.HistogramVec
+	aborts             *prometheus.CounterVec
+	retries            prometheus.Counter
+	validationFailures *prometheus.CounterVec
+	bindErrors         *prometheus.CounterVec
+}
+
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "dgraph",
+			Name:      "operations_total",
+			Help:      "Dgraph operations by type (mutate, query, commit, do) and status.",
+		}, []string{"op", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "dgraph",
+			Name:      "operation_duration_seconds",
+			Help:      "Client side duration of dgraph operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"op"}),
+		serverLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "dgraph",
+			Name:      "server_latency_seconds",
+			Help:      "Server side latency reported by dgraph, by phase (parsing, processing, encoding).",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"op", "phase"}),
+		aborts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "dgraph",
+			Name:      "aborts_total",
+			Help:      "Aborted dgraph transactions.",
+		}, []string{"op"}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "dgraph",
+			Name:      "retries_total",
+			Help:      "Transactions retried after abort.",
+		}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "binder",
+			Name:      "validation_failures_total",
+			Help:      "Saves rejected by BinderValidate, by model.",
+		}, []string{"model"}),
+		bindErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "binder",
+			Name:      "bind_errors_total",
+			Help:      "BindFieldValue failures, by field.",
+		}, []string{"field"}),
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.operations, c.duration, c.serverLatency, c.aborts, c.retries, c.validationFailures, c.bindErrors,
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+func (c *Collector) ObserveOperation(op string, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	c.operations.WithLabelValues(op, status).Inc()
+	c.duration.WithLabelValues(op).Observe(duration.Seconds())
+}
+
+func (c *Collector) ObserveServerLatency(op string, parsing time.Duration, processing time.Duration, encoding time.Duration) {
+	c.serverLatency.WithLabelValues(op, "parsing").Observe(parsing.Seconds())
+	c.serverLatency.WithLabelValues(op, "processing").Observe(processing.Seconds())
+	c.serverLatency.WithLabelValues(op, "encoding").Observe(encoding.Seconds())
+}
+
+func (c *Collector) IncAbort(op string) {
+	c.aborts.WithLabelValues(op).Inc()
+}
+
+func (c *Collector) IncRetry() {
+	c.retries.Inc()
+}
+
+func (c *Collector) IncValidationFailure(model string) {
+	c.validationFailures.WithLabelValues(model).Inc()
+}
+
+func (c *Collector) IncBindError(field string) {
+	c.bindErrors.WithLabelValues(field).Inc()
+}
@@ -0,0 +1,52 @@
+package metrics_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/u007/gobinder/lib"
+	"github.com/u007/gobinder/metrics"
+)
+
+func TestCollector(t *testing.T) {
+	collector := metrics.NewCollector("test")
+	var _ lib.Metrics = collector
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, registry.Register(collector))
+
+	collector.ObserveOperation("mutate", 10*time.Millisecond, nil)
+	collector.ObserveOperation("mutate", 10*time.Millisecond, errors.New("aborted"))
+	collector.IncAbort("commit")
+	collector.IncRetry()
+	collector.IncValidationFailure("test_models")
+	collector.IncBindError("Start_at")
+	collector.ObserveServerLatency("query", time.Millisecond, 2*time.Millisecond, time.Millisecond)
+
+	expected := `
+# HELP test_dgraph_operations_total Dgraph operations by type (mutate, query, commit, do) and status.
+# TYPE test_dgraph_operations_total counter
+test_dgraph_operations_total{op="mutate",status="error"} 1
+test_dgraph_operations_total{op="mutate",status="ok"} 1
+# HELP test_dgraph_aborts_total Aborted dgraph transactions.
+# TYPE test_dgraph_aborts_total counter
+test_dgraph_aborts_total{op="commit"} 1
+# HELP test_dgraph_retries_total Transactions retried after abort.
+# TYPE test_dgraph_retries_total counter
+test_dgraph_retries_total 1
+# HELP test_binder_validation_failures_total Saves rejected by BinderValidate, by model.
+# TYPE test_binder_validation_failures_total counter
+test_binder_validation_failures_total{model="test_models"} 1
+# HELP test_binder_bind_errors_total BindFieldValue failures, by field.
+# TYPE test_binder_bind_errors_total counter
+test_binder_bind_errors_total{field="Start_at"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"test_dgraph_operations_total", "test_dgraph_aborts_total", "test_dgraph_retries_total",
+		"test_binder_validation_failures_total", "test_binder_bind_errors_total"))
+	assert.Equal(t, 3, testutil.CollectAndCount(collector, "test_dgraph_server_latency_seconds"))
+}
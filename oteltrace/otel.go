@@ -0,0 +1,68 @@
+package oteltrace
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/u007/gobinder/lib"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// lib.Tracer backed by an OpenTelemetry tracer
+// usage: config.Tracer = oteltrace.New(otel.Tracer("gobinder"))
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+func New(tracer trace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...lib.Attribute) (context.Context, lib.Span) {
+	ctx, otelSpan := t.tracer.Start(ctx, name, trace.WithAttributes(convert(attrs)...))
+	return ctx, &span{span: otelSpan}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s *span) SetAttributes(attrs ...lib.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+func (s *span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s *span) End() {
+	s.span.End()
+}
+
+func convert(attrs []lib.Attribute) []attribute.KeyValue {
+	result := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch value := attr.Value.(type) {
+		case string:
+			result = append(result, attribute.String(attr.Key, value))
+		case bool:
+			result = append(result, attribute.Bool(attr.Key, value))
+		case int:
+			result = append(result, attribute.Int(attr.Key, value))
+		case int32:
+			result = append(result, attribute.Int64(attr.Key, int64(value)))
+		case int64:
+			result = append(result, attribute.Int64(attr.Key, value))
+		case float64:
+			result = append(result, attribute.Float64(attr.Key, value))
+		case []string:
+			result = append(result, attribute.StringSlice(attr.Key, value))
+		default:
+			result = append(result, attribute.String(attr.Key, fmt.Sprintf("%v", value)))
+		}
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package oteltrace_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/u007/gobinder/lib"
+	"github.com/u007/gobinder/oteltrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracerSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := oteltrace.New(provider.Tracer("gobinder"))
+
+	ctx, parent := tracer.Start(context.Background(), "binder.SaveBinder",
+		lib.Attr("model", "test_models"), lib.Attr("action", "create"))
+	_, child := tracer.Start(ctx, "dgraph.Mutate", lib.Attr("nquads", 3))
+	lib.EndSpan(child, errors.New("aborted"))
+	parent.SetAttributes(lib.Attr("uid", "0x1"))
+	lib.EndSpan(parent, nil)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "dgraph.Mutate", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Contains(t, spans[0].Attributes, attribute.Int("nquads", 3))
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+	assert.Equal(t, "binder.SaveBinder", spans[1].Name)
+	assert.Contains(t, spans[1].Attributes, attribute.String("uid", "0x1"))
+	assert.Contains(t, spans[1].Attributes, attribute.String("model", "test_models"))
+}
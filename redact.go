@@ -0,0 +1,198 @@
+package gobinder
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// field names or json names (case insensitive) redacted from logs, errors and change exports
+// in addition to fields tagged `binder:"sensitive"`
+var RedactedFields = []string{"password"}
+
+const RedactedValue = "[REDACTED]"
+
+type redactContextKey int
+
+const sensitiveKey redactContextKey = iota
+
+// field tagged `binder:"sensitive"`, `dgraph:"password"` or listed in RedactedFields
+func IsSensitiveField(field reflect.StructField) bool {
+	if field.Tag.Get("binder") == "sensitive" || field.Tag.Get("dgraph") == "password" {
+		return true
+	}
+	return IsSensitiveName(field.Name) || IsSensitiveName(field.Tag.Get("json"))
+}
+
+// name listed in RedactedFields
+func IsSensitiveName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, redacted := range RedactedFields {
+		if strings.EqualFold(redacted, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// value to be logged for field name
+func RedactValue(name string, value interface{}) interface{} {
+	if IsSensitiveName(name) {
+		return RedactedValue
+	}
+	return value
+}
+
+// copy of hash (keyed by json name) with sensitive fields of model redacted
+func RedactHash(model interface{}, hash map[string]interface{}) map[string]interface{} {
+	modelType := reflect.Indirect(reflect.ValueOf(model)).Type()
+	result := make(map[string]interface{}, len(hash))
+	for key, value := range hash {
+		result[key] = RedactValue(key, value)
+	}
+	if modelType.Kind() != reflect.Struct {
+		return result
+	}
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if _, has := result[field.Tag.Get("json")]; has && IsSensitiveField(field) {
+			result[field.Tag.Get("json")] = RedactedValue
+		}
+	}
+	return result
+}
+
+// model as map of json name to value with sensitive fields redacted, for logging
+// relations (pointed structs, slices and maps) are redacted too
+func Redacted(model interface{}) interface{} {
+	return redacted(reflect.ValueOf(model), 0)
+}
+
+// depth guards against reference cycles
+const redactMaxDepth = 16
+
+func redacted(val reflect.Value, depth int) interface{} {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return val.Interface()
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		return nil
+	}
+	if depth > redactMaxDepth || !val.CanInterface() {
+		return RedactedValue
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		if val.Type().String() == "time.Time" {
+			return val.Interface()
+		}
+		result := map[string]interface{}{}
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := field.Tag.Get("json")
+			if name == "" {
+				name = field.Name
+			}
+			if IsSensitiveField(field) {
+				result[name] = RedactedValue
+				continue
+			}
+			result[name] = redacted(val.Field(i), depth+1)
+		}
+		return result
+	case reflect.Slice, reflect.Array:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return val.Interface() //[]byte, uuid
+		}
+		if val.Kind() == reflect.Slice && val.IsNil() {
+			return val.Interface()
+		}
+		result := make([]interface{}, val.Len())
+		for i := 0; i < val.Len(); i++ {
+			result[i] = redacted(val.Index(i), depth+1)
+		}
+		return result
+	case reflect.Map:
+		if val.IsNil() {
+			return val.Interface()
+		}
+		result := make(map[string]interface{}, val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if IsSensitiveName(key) {
+				result[key] = RedactedValue
+				continue
+			}
+			result[key] = redacted(iter.Value(), depth+1)
+		}
+		return result
+	}
+	return val.Interface()
+}
+
+// Changes with values of sensitive fields redacted, for logs and exports
+func (this *ModelBinder) RedactedChanges() map[string][]interface{} {
+	changes := this.ChangesSnapshot()
+	for name := range changes {
+		if this.isSensitive(name) {
+			changes[name] = []interface{}{RedactedValue, RedactedValue}
+		}
+	}
+	return changes
+}
+
+// keep field name changed but drop its recorded values, example: password plaintext after save
+func (this *ModelBinder) ClearChange(name string) {
+	defer this.writeLock()()
+	if _, ok := this.Changes[name]; !ok {
+		return
+	}
+	field, ok := reflect.TypeOf(this.model).Elem().FieldByName(name)
+	if !ok {
+		delete(this.Changes, name)
+		return
+	}
+	zero := reflect.Zero(field.Type).Interface()
+	this.Changes[name] = []interface{}{zero, zero}
+}
+
+func (this *ModelBinder) isSensitive(name string) bool {
+	field, ok := reflect.TypeOf(this.model).Elem().FieldByName(name)
+	if !ok {
+		return IsSensitiveName(name)
+	}
+	return IsSensitiveField(field)
+}
+
+// value of field name for logs and error messages
+func (this *ModelBinder) redactValue(name string, value interface{}) interface{} {
+	if this.isSensitive(name) {
+		return RedactedValue
+	}
+	return value
+}
+
+// mark context as binding a sensitive field, values are redacted by BindFieldValue
+func withSensitive(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sensitiveKey, true)
+}
+
+func redactFor(ctx context.Context, name string, value interface{}) interface{} {
+	if ctx != nil {
+		if sensitive, _ := ctx.Value(sensitiveKey).(bool); sensitive {
+			return RedactedValue
+		}
+	}
+	return RedactValue(name, value)
+}
@@ -0,0 +1,182 @@
+package gobinder
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/u007/gobinder/lib"
+)
+
+// element level changes of a relation field, elements are keyed by UID
+// elements without UID are matched by value: unmatched new ones are added, unmatched old ones removed
+type RelationChange struct {
+	Added    []interface{} //new elements, or elements without UID
+	Removed  []interface{} //old elements, including elements without UID
+	Modified []interface{} //same UID, different value
+}
+
+func (c *RelationChange) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// UIDs of added elements, elements without UID are skipped, as in RemovedUIDs
+func (c *RelationChange) AddedUIDs() []string {
+	return relationUIDs(c.Added)
+}
+
+func (c *RelationChange) RemovedUIDs() []string {
+	return relationUIDs(c.Removed)
+}
+
+func (c *RelationChange) ModifiedUIDs() []string {
+	return relationUIDs(c.Modified)
+}
+
+// compute added, removed and modified elements of a relation field (struct or slices of struct)
+// from the recorded [old, new] change
+func (this *ModelBinder) RelationChanges(name string) (*RelationChange, error) {
+	defer this.readLock()()
+	model := reflect.ValueOf(this.model).Elem()
+	field := model.FieldByName(name)
+	if !field.IsValid() {
+		return nil, fmt.Errorf("Cannot find field: %v:%s", model.Type().Name(), name)
+	}
+
+	if !isRelationType(field.Type()) {
+		return nil, fmt.Errorf("Field is not a relation: %v:%s", model.Type().Name(), name)
+	}
+
+	result := &RelationChange{}
+	change, has := this.Changes[name]
+	if !has {
+		return result, nil
+	}
+
+	oldRows := relationElements(change[0])
+	newRows := relationElements(change[1])
+
+	oldByUID := map[string]reflect.Value{}
+	oldUnkeyed := []reflect.Value{}
+	for _, row := range oldRows {
+		if uid := relationUID(row); uid != "" {
+			oldByUID[uid] = row
+		} else {
+			oldUnkeyed = append(oldUnkeyed, row)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, row := range newRows {
+		uid := relationUID(row)
+		if uid == "" {
+			matched := -1
+			for i, oldRow := range oldUnkeyed {
+				if eq, err := lib.IsEqualValue(this.Context, oldRow.Interface(), row.Interface()); err != nil {
+					return nil, err
+				} else if eq {
+					matched = i
+					break
+				}
+			}
+			if matched >= 0 {
+				oldUnkeyed = append(oldUnkeyed[:matched], oldUnkeyed[matched+1:]...)
+				continue
+			}
+			result.Added = append(result.Added, row.Interface())
+			continue
+		}
+		seen[uid] = true
+
+		oldRow, ok := oldByUID[uid]
+		if !ok {
+			result.Added = append(result.Added, row.Interface())
+			continue
+		}
+
+		if eq, err := lib.IsEqualValue(this.Context, oldRow.Interface(), row.Interface()); err != nil {
+			return nil, err
+		} else if !eq {
+			result.Modified = append(result.Modified, row.Interface())
+		}
+	}
+
+	for _, row := range oldRows {
+		uid := relationUID(row)
+		if uid == "" || seen[uid] {
+			continue
+		}
+		result.Removed = append(result.Removed, row.Interface())
+	}
+	for _, row := range oldUnkeyed {
+		result.Removed = append(result.Removed, row.Interface())
+	}
+
+	return result, nil
+}
+
+// struct or slice of struct, through pointers, except time.Time and Geo
+// interface{} is not a relation
+func isRelationType(fieldType reflect.Type) bool {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() == reflect.Slice {
+		fieldType = fieldType.Elem()
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+	}
+	return fieldType.Kind() == reflect.Struct && fieldType.String() != "time.Time" && fieldType != reflect.TypeOf(Geo{})
+}
+
+// flatten *[]Class, []*Class, *Class or Class into list of struct values
+func relationElements(value interface{}) []reflect.Value {
+	val := reflect.ValueOf(value)
+	for val.IsValid() && val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		return nil
+	}
+
+	if val.Kind() == reflect.Struct {
+		return []reflect.Value{val}
+	}
+
+	rows := []reflect.Value{}
+	if val.Kind() != reflect.Slice {
+		return rows
+	}
+	for i := 0; i < val.Len(); i++ {
+		row := val.Index(i)
+		if row.Kind() == reflect.Ptr {
+			if row.IsNil() {
+				continue
+			}
+			row = row.Elem()
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func relationUID(row reflect.Value) string {
+	uid := row.FieldByName("UID")
+	if !uid.IsValid() || uid.Kind() != reflect.String {
+		return ""
+	}
+	return uid.String()
+}
+
+func relationUIDs(rows []interface{}) []string {
+	uids := []string{}
+	for _, row := range rows {
+		if uid := relationUID(reflect.Indirect(reflect.ValueOf(row))); uid != "" {
+			uids = append(uids, uid)
+		}
+	}
+	return uids
+}
@@ -93,12 +93,14 @@ func (a *TestSuite) SetupTest() {
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestTestSuite(t *testing.T) {
-	var ctx context.Context //:= plush.NewContext()
-	logger := gobinder.SetupLogging()
-	ctx = context.WithValue(ctx, "log", *logger)
+	logger, _, err := gobinder.SetupLogging()
+	if err != nil {
+		panic(err)
+	}
+	ctx := gobinder.WithLogger(context.Background(), logger)
 	testS := new(TestSuite)
 
-	testS.Logger = *logger
+	testS.Logger = logger
 	testS.Context = plush.NewContextWithContext(ctx)
 	suite.Run(t, testS)
 }
@@ -2,4 +2,4 @@
 
 cd docker && docker-compose up -d
 cd ..
-GO_ENV=test go test dgraph/*test.go
+GO_ENV=test go test -race dgraph/*test.go
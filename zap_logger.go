@@ -2,56 +2,202 @@ package gobinder
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gobuffalo/envy"
+	"github.com/u007/gobinder/lib"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 var ENV = envy.Get("GO_ENV", "development")
 
-func SetupLogging() *zap.SugaredLogger {
-	var logger *zap.Logger
-	var err error
+type LoggingOptions struct {
+	Level             string // debug, info, warn, error
+	Encoding          string // console or json
+	OutputPaths       []string
+	ErrorOutputPaths  []string
+	Development       bool
+	Color             bool
+	DisableCaller     bool
+	DisableStacktrace bool
+	TimeKey           string              // empty to omit time
+	Sampling          *zap.SamplingConfig // nil to disable
+}
 
-	switch ENV {
+// options used by SetupLogging for GO_ENV: production, staging, test or development
+func DefaultLoggingOptions(env string) LoggingOptions {
+	switch env {
 	case "production":
-		config := zap.NewProductionConfig()
-		config.Encoding = "console"
-		config.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
-
-		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		config.EncoderConfig.TimeKey = ""
-		config.OutputPaths = []string{"log/production.log"}
-		config.ErrorOutputPaths = []string{"log/production.log", "log/error.log"}
-		logger, err = config.Build()
+		return LoggingOptions{
+			Level:            "info",
+			Encoding:         "console",
+			OutputPaths:      []string{"log/production.log"},
+			ErrorOutputPaths: []string{"log/production.log", "log/error.log"},
+			Color:            true,
+			Sampling:         &zap.SamplingConfig{Initial: 100, Thereafter: 100},
+		}
 	case "staging":
-		config := zap.NewDevelopmentConfig()
-		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		config.EncoderConfig.TimeKey = ""
-		config.OutputPaths = []string{"log/staging.log"}
-		config.ErrorOutputPaths = []string{"log/staging.log", "log/error.log"}
-		logger, err = config.Build()
-	default:
-		config := zap.NewDevelopmentConfig()
-		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		config.EncoderConfig.TimeKey = ""
-		config.DisableStacktrace = false
-		if ENV == "test" {
-			config.OutputPaths = []string{"stdout"}
-			// pop.Debug = true
-			// log.SetOutput(os.Stdout)
+		return LoggingOptions{
+			Level:            "debug",
+			Encoding:         "console",
+			OutputPaths:      []string{"log/staging.log"},
+			ErrorOutputPaths: []string{"log/staging.log", "log/error.log"},
+			Development:      true,
+			Color:            true,
+		}
+	case "test":
+		return LoggingOptions{
+			Level:            "debug",
+			Encoding:         "console",
+			OutputPaths:      []string{"stdout"},
+			ErrorOutputPaths: []string{"stderr"},
+			Development:      true,
+			Color:            true,
+		}
+	}
+	return LoggingOptions{
+		Level:            "debug",
+		Encoding:         "console",
+		OutputPaths:      []string{"stderr"},
+		ErrorOutputPaths: []string{"stderr"},
+		Development:      true,
+		Color:            true,
+	}
+}
+
+// override options by environment:
+//
+//	LOG_LEVEL=debug|info|warn|error
+//	LOG_ENCODING=console|json
+//	LOG_OUTPUT=stdout,log/app.log
+//	LOG_ERROR_OUTPUT=stderr,log/error.log
+//	LOG_SAMPLING=100,100 (initial,thereafter) or off
+//	LOG_CALLER=true|false
+//	LOG_COLOR=true|false
+func LoggingOptionsFromEnv(opts LoggingOptions) (LoggingOptions, error) {
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		opts.Level = level
+	}
+	if encoding := os.Getenv("LOG_ENCODING"); encoding != "" {
+		opts.Encoding = encoding
+	}
+	if output := os.Getenv("LOG_OUTPUT"); output != "" {
+		opts.OutputPaths = splitPaths(output)
+	}
+	if output := os.Getenv("LOG_ERROR_OUTPUT"); output != "" {
+		opts.ErrorOutputPaths = splitPaths(output)
+	}
+	if sampling := os.Getenv("LOG_SAMPLING"); sampling != "" {
+		if sampling == "off" {
+			opts.Sampling = nil
+		} else {
+			parts := strings.Split(sampling, ",")
+			if len(parts) != 2 {
+				return opts, fmt.Errorf("invalid LOG_SAMPLING %s, expected initial,thereafter", sampling)
+			}
+			initial, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+			if err != nil {
+				return opts, fmt.Errorf("invalid LOG_SAMPLING %s: %+v", sampling, err)
+			}
+			thereafter, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return opts, fmt.Errorf("invalid LOG_SAMPLING %s: %+v", sampling, err)
+			}
+			opts.Sampling = &zap.SamplingConfig{Initial: initial, Thereafter: thereafter}
+		}
+	}
+	if caller := os.Getenv("LOG_CALLER"); caller != "" {
+		enabled, err := strconv.ParseBool(caller)
+		if err != nil {
+			return opts, fmt.Errorf("invalid LOG_CALLER %s: %+v", caller, err)
+		}
+		opts.DisableCaller = !enabled
+	}
+	if color := os.Getenv("LOG_COLOR"); color != "" {
+		enabled, err := strconv.ParseBool(color)
+		if err != nil {
+			return opts, fmt.Errorf("invalid LOG_COLOR %s: %+v", color, err)
+		}
+		opts.Color = enabled
+	}
+	return opts, nil
+}
+
+// build logger from options
+// @return logger, level which can be changed at runtime (and served over http), error
+func NewLogging(opts LoggingOptions) (*zap.SugaredLogger, zap.AtomicLevel, error) {
+	level := zap.NewAtomicLevel()
+	if opts.Level != "" {
+		if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+			return nil, level, fmt.Errorf("invalid log level %s: %+v", opts.Level, err)
 		}
-		logger, err = config.Build()
-		// logger, err = zap.NewDevelopment()
 	}
+	if opts.Encoding != "" && opts.Encoding != "console" && opts.Encoding != "json" {
+		return nil, level, fmt.Errorf("invalid log encoding %s", opts.Encoding)
+	}
+
+	var config zap.Config
+	if opts.Development {
+		config = zap.NewDevelopmentConfig()
+	} else {
+		config = zap.NewProductionConfig()
+	}
+	config.Level = level
+	if opts.Encoding != "" {
+		config.Encoding = opts.Encoding
+	}
+	if len(opts.OutputPaths) > 0 {
+		config.OutputPaths = opts.OutputPaths
+	}
+	if len(opts.ErrorOutputPaths) > 0 {
+		config.ErrorOutputPaths = opts.ErrorOutputPaths
+	}
+	if opts.Color && config.Encoding == "console" {
+		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+	config.EncoderConfig.TimeKey = opts.TimeKey
+	config.DisableCaller = opts.DisableCaller
+	config.DisableStacktrace = opts.DisableStacktrace
+	config.Sampling = opts.Sampling
+
+	logger, err := config.Build()
+	if err != nil {
+		return nil, level, fmt.Errorf("unable to initialize logger: %s", err.Error())
+	}
+	return logger.Sugar(), level, nil
+}
 
+// logger for GO_ENV, overridden by LOG_* environment, see LoggingOptionsFromEnv
+func SetupLogging() (*zap.SugaredLogger, zap.AtomicLevel, error) {
+	opts, err := LoggingOptionsFromEnv(DefaultLoggingOptions(ENV))
 	if err != nil {
-		panic(fmt.Sprintf("unable to initialize logger: %s", err.Error()))
+		return nil, zap.NewAtomicLevel(), err
 	}
+	return NewLogging(opts)
+}
 
-	// Logger = logger.WithOptions(zap.AddCallerSkip(1)).Sugar()
+func splitPaths(value string) []string {
+	paths := []string{}
+	for _, path := range strings.Split(value, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// structured logger on top of zap, also usable as printf style lib.Logger
+type zapLogger struct {
+	*zap.SugaredLogger
+}
+
+func NewZapStructuredLogger(logger *zap.SugaredLogger) lib.StructuredLogger {
+	return &zapLogger{logger}
+}
 
-	defer logger.Sync()
-	return logger.Sugar()
+func (l *zapLogger) With(keysAndValues ...interface{}) lib.StructuredLogger {
+	return &zapLogger{l.SugaredLogger.With(keysAndValues...)}
 }
@@ -0,0 +1,46 @@
+package gobinder_test
+
+import (
+	"github.com/u007/gobinder"
+	"go.uber.org/zap"
+)
+
+func (a *TestSuite) TestLoggingOptionsFromEnv() {
+	a.Equal("info", gobinder.DefaultLoggingOptions("production").Level)
+
+	//restored by t.Setenv when test ends
+	t := a.T()
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_ENCODING", "json")
+	t.Setenv("LOG_OUTPUT", "stdout, log/app.log")
+	t.Setenv("LOG_SAMPLING", "off")
+	t.Setenv("LOG_CALLER", "false")
+
+	opts, err := gobinder.LoggingOptionsFromEnv(gobinder.DefaultLoggingOptions("production"))
+	a.NoError(err)
+	a.Equal("warn", opts.Level)
+	a.Equal("json", opts.Encoding)
+	a.Equal([]string{"stdout", "log/app.log"}, opts.OutputPaths)
+	a.Equal([]string{"log/production.log", "log/error.log"}, opts.ErrorOutputPaths)
+	a.Nil(opts.Sampling)
+	a.True(opts.DisableCaller)
+
+	t.Setenv("LOG_SAMPLING", "abc")
+	_, err = gobinder.LoggingOptionsFromEnv(gobinder.DefaultLoggingOptions("production"))
+	a.True(err != nil)
+}
+
+func (a *TestSuite) TestNewLoggingLevel() {
+	opts := gobinder.DefaultLoggingOptions("test")
+	opts.Level = "info"
+	logger, level, err := gobinder.NewLogging(opts)
+	a.NoError(err)
+	a.NotNil(logger)
+	a.False(level.Enabled(zap.DebugLevel))
+	level.SetLevel(zap.DebugLevel)
+	a.True(level.Enabled(zap.DebugLevel))
+
+	opts.Encoding = "xml"
+	_, _, err = gobinder.NewLogging(opts)
+	a.True(err != nil)
+}